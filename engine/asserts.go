@@ -24,7 +24,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/store/tooling"
@@ -48,56 +51,161 @@ func readRef(name string) (*snapRef, error) {
 	return &ref, nil
 }
 
-func writeJSON(name, what string, v interface{}) error {
+// revisionChange is a change of a snap or declaration revision, with -1
+// standing for unknown.
+type revisionChange struct {
+	Before int `json:"before"`
+	After  int `json:"after"`
+}
+
+// readRevision reads a revision file of a snap directory, it returns -1
+// if it is missing or does not hold a revision, e.g. for local snaps.
+func readRevision(name, what string) int {
+	b, err := ioutil.ReadFile(filepath.Join(name, what))
+	if err != nil {
+		return -1
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func writeRevision(name, what string, n int) error {
+	return writeSnapDirFile(name, what, []byte(strconv.Itoa(n)+"\n"))
+}
+
+// writeSnapDirFile writes a file of a snap directory, replacing it
+// instead of writing through it in case it is a link into the fetch
+// cache.
+func writeSnapDirFile(name, what string, b []byte) error {
+	fn := filepath.Join(name, what)
+	if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return ioutil.WriteFile(fn, b, 0644)
+}
+
+func marshalJSON(name, what string, v interface{}) ([]byte, error) {
 	b, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
-		return fmt.Errorf("cannot marshal %q for %q: %v", what, name, err)
+		return nil, fmt.Errorf("cannot marshal %q for %q: %v", what, name, err)
 	}
 	buf := bytes.NewBuffer(b)
 	buf.WriteString("\n")
-	return ioutil.WriteFile(filepath.Join(name, what), buf.Bytes(), 0644)
+	return buf.Bytes(), nil
+}
+
+func writeJSON(name, what string, v interface{}) error {
+	b, err := marshalJSON(name, what, v)
+	if err != nil {
+		return err
+	}
+	return writeSnapDirFile(name, what, b)
 }
 
 func fetchDecls(param *json.RawMessage) error {
 	var params struct {
 		Snaps []string `json:"snaps"`
+		// StoreURL points to an alternative store, e.g. a mock-store
+		StoreURL string `json:"store-url"`
 	}
 
 	if err := json.Unmarshal([]byte(*param), &params); err != nil {
 		return err
 	}
 
+	if params.StoreURL != "" {
+		// picked up by the tooling store
+		os.Setenv("UBUNTU_STORE_URL", params.StoreURL)
+	}
+
 	tsto, err := tooling.NewToolingStore()
 	if err != nil {
 		return err
 	}
 
 	for _, name := range params.Snaps {
-		ref, err := readRef(name)
-		if err != nil {
+		if _, err := fetchDecl(tsto, nil, name, false); err != nil {
 			return err
 		}
-		a, err := tsto.Find(asserts.SnapDeclarationType, map[string]string{
-			"series":  "16",
-			"snap-id": ref.SnapID,
-		})
+	}
+	return nil
+}
+
+// fetchDecl writes the rules of a snap directory from the
+// snap-declaration in the store, see writeDeclRules. When refreshing they
+// are only rewritten if the declaration revision changed. It returns the
+// revision change, if any.
+func fetchDecl(tsto *tooling.ToolingStore, cache *fetchCache, name string, refresh bool) (*revisionChange, error) {
+	ref, err := readRef(name)
+	if err != nil {
+		return nil, err
+	}
+	a, err := tsto.Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  "16",
+		"snap-id": ref.SnapID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	decl := a.(*asserts.SnapDeclaration)
+	change := &revisionChange{
+		Before: readRevision(name, "decl-revision"),
+		After:  decl.Revision(),
+	}
+	if refresh && change.Before == change.After {
+		return nil, nil
+	}
+	hdrs := decl.Headers()
+	rules := make(map[string][]byte)
+	for _, side := range []string{"plugs", "slots"} {
+		v, ok := hdrs[side]
+		if !ok {
+			continue
+		}
+		b, err := marshalJSON(name, side+".json", v)
 		if err != nil {
+			return nil, err
+		}
+		rules[side+".json"] = b
+	}
+	if err := writeDeclRules(cache, name, ref.SnapID, change.After, rules); err != nil {
+		return nil, err
+	}
+	if change.Before == change.After {
+		return nil, nil
+	}
+	return change, nil
+}
+
+// writeDeclRules writes the plugs.json and slots.json of a snap directory
+// and the decl-revision, rules for sides missing from the declaration
+// are removed. With a cache the rules are linked from it.
+func writeDeclRules(cache *fetchCache, name, snapID string, declRevision int, rules map[string][]byte) error {
+	if cache != nil {
+		entry := cache.declEntry(snapID, declRevision)
+		if err := cache.put(entry, rules); err != nil {
 			return err
 		}
-		decl := a.(*asserts.SnapDeclaration)
-		hdrs := decl.Headers()
-		plugs, plugsOK := hdrs["plugs"]
-		slots, slotsOK := hdrs["slots"]
-		if plugsOK {
-			if err := writeJSON(name, "plugs.json", plugs); err != nil {
-				return err
-			}
+		if err := cache.link(name, entry, "plugs.json", "slots.json"); err != nil {
+			return err
 		}
-		if slotsOK {
-			if err := writeJSON(name, "slots.json", slots); err != nil {
+	} else {
+		for _, what := range []string{"plugs.json", "slots.json"} {
+			b, ok := rules[what]
+			if !ok {
+				// the side was dropped from the declaration
+				if err := os.Remove(filepath.Join(name, what)); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+				continue
+			}
+			if err := writeSnapDirFile(name, what, b); err != nil {
 				return err
 			}
 		}
 	}
-	return nil
+	return writeRevision(name, "decl-revision", declRevision)
 }
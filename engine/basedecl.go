@@ -0,0 +1,236 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+// leading headers in the order they are conventionally written
+var leadingHeaders = []string{"type", "authority-id", "series", "revision"}
+
+func sortedHeaderKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeHeaderValue(b *bytes.Buffer, v interface{}, indent string) error {
+	switch x := v.(type) {
+	case []interface{}:
+		b.WriteString("\n")
+		for _, elem := range x {
+			b.WriteString(indent + "  -")
+			if err := writeHeaderValue(b, elem, indent+"  "); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		b.WriteString("\n")
+		for _, k := range sortedHeaderKeys(x) {
+			b.WriteString(indent + "  " + k + ":")
+			if err := writeHeaderValue(b, x[k], indent+"  "); err != nil {
+				return err
+			}
+		}
+	case string:
+		b.WriteString(" " + x + "\n")
+	default:
+		// assertion headers have only strings as scalars
+		return fmt.Errorf("unsupported header value %v of type %T, expected a string", x, x)
+	}
+	return nil
+}
+
+// encodeHeaders encodes assertion headers in the assertion text format.
+func encodeHeaders(headers map[string]interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	done := make(map[string]bool, len(leadingHeaders))
+	for _, k := range leadingHeaders {
+		if v, ok := headers[k]; ok {
+			b.WriteString(k + ":")
+			if err := writeHeaderValue(&b, v, ""); err != nil {
+				return nil, fmt.Errorf("header %s: %v", k, err)
+			}
+			done[k] = true
+		}
+	}
+	for _, k := range sortedHeaderKeys(headers) {
+		if done[k] {
+			continue
+		}
+		b.WriteString(k + ":")
+		if err := writeHeaderValue(&b, headers[k], ""); err != nil {
+			return nil, fmt.Errorf("header %s: %v", k, err)
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// baseDeclFromHeaders builds a base-declaration from its headers, the
+// base-declaration is never signed so a dummy signature is used.
+func baseDeclFromHeaders(headers map[string]interface{}) (*asserts.BaseDeclaration, error) {
+	text, err := encodeHeaders(headers)
+	if err != nil {
+		return nil, err
+	}
+	a, err := asserts.Decode(append(text, []byte("\nAXNpZw==")...))
+	if err != nil {
+		return nil, err
+	}
+	return a.(*asserts.BaseDeclaration), nil
+}
+
+// useBaseDeclaration replaces the builtin base-declaration, used by the
+// interface manager and the policy checks, with the one in the file.
+// The file has the base-declaration headers in the assertion format.
+func useBaseDeclaration(fn string) error {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+	if err := asserts.InitBuiltinBaseDeclaration(b); err != nil {
+		return fmt.Errorf("cannot use base-declaration from %s: %v", fn, err)
+	}
+	return nil
+}
+
+type baseDeclarationParams struct {
+	// BaseDeclaration is the path of the override base-declaration,
+	// if any
+	BaseDeclaration string `json:"base-declaration"`
+	// Interfaces restricts the stanzas to the given interfaces
+	Interfaces []string `json:"interfaces"`
+}
+
+// baseDeclStanza is the rule for an interface on one side in the builtin
+// base-declaration and in the override.
+type baseDeclStanza struct {
+	Interface string `json:"interface"`
+	Side      string `json:"side"`
+
+	Builtin     interface{} `json:"builtin"`
+	BuiltinText string      `json:"builtin-text"`
+
+	Override     interface{} `json:"override,omitempty"`
+	OverrideText string      `json:"override-text,omitempty"`
+	Changed      bool        `json:"changed"`
+}
+
+type baseDeclarationResult struct {
+	Stanzas []baseDeclStanza `json:"stanzas"`
+}
+
+func stanzaText(side, iface string, rule interface{}) (string, error) {
+	if rule == nil {
+		return "", nil
+	}
+	text, err := encodeHeaders(map[string]interface{}{
+		side: map[string]interface{}{iface: rule},
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s %s rule: %v", side, iface, err)
+	}
+	return string(text), nil
+}
+
+func compareBaseDeclarations(params *baseDeclarationParams) (*baseDeclarationResult, error) {
+	builtin := asserts.BuiltinBaseDeclaration().Headers()
+	var override map[string]interface{}
+	if params.BaseDeclaration != "" {
+		if err := useBaseDeclaration(params.BaseDeclaration); err != nil {
+			return nil, err
+		}
+		override = asserts.BuiltinBaseDeclaration().Headers()
+	}
+
+	rules := func(headers map[string]interface{}, side string) map[string]interface{} {
+		m, _ := headers[side].(map[string]interface{})
+		return m
+	}
+	res := &baseDeclarationResult{}
+	for _, side := range []string{"plugs", "slots"} {
+		ifaces := params.Interfaces
+		if len(ifaces) == 0 {
+			all := make(map[string]interface{})
+			for iface := range rules(builtin, side) {
+				all[iface] = true
+			}
+			for iface := range rules(override, side) {
+				all[iface] = true
+			}
+			ifaces = sortedHeaderKeys(all)
+		}
+		for _, iface := range ifaces {
+			stanza := baseDeclStanza{
+				Interface: iface,
+				Side:      side,
+				Builtin:   rules(builtin, side)[iface],
+			}
+			var err error
+			stanza.BuiltinText, err = stanzaText(side, iface, stanza.Builtin)
+			if err != nil {
+				return nil, err
+			}
+			if override != nil {
+				stanza.Override = rules(override, side)[iface]
+				stanza.OverrideText, err = stanzaText(side, iface, stanza.Override)
+				if err != nil {
+					return nil, err
+				}
+				stanza.Changed = !reflect.DeepEqual(stanza.Builtin, stanza.Override)
+			}
+			if stanza.Builtin == nil && stanza.Override == nil {
+				continue
+			}
+			res.Stanzas = append(res.Stanzas, stanza)
+		}
+	}
+	return res, nil
+}
+
+// Operations
+
+func baseDeclaration(param *json.RawMessage) error {
+	var params baseDeclarationParams
+	if err := json.Unmarshal([]byte(*param), &params); err != nil {
+		return err
+	}
+
+	res, err := compareBaseDeclarations(&params)
+	if err != nil {
+		return outputError("json", err)
+	}
+
+	b, err := json.Marshal(res)
+	noerror(err)
+	fmt.Println(string(b))
+	return nil
+}
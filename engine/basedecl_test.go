@@ -0,0 +1,81 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"testing"
+)
+
+func TestEncodeHeaders(t *testing.T) {
+	headers := map[string]interface{}{
+		"slots": map[string]interface{}{
+			"network": map[string]interface{}{
+				"allow-installation": map[string]interface{}{
+					"slot-snap-type": []interface{}{"core", "gadget"},
+				},
+			},
+		},
+		"plugs": map[string]interface{}{
+			"x11": map[string]interface{}{
+				"allow-auto-connection": "true",
+			},
+		},
+		"series":       "16",
+		"authority-id": "canonical",
+		"type":         "base-declaration",
+	}
+	b, err := encodeHeaders(headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `type: base-declaration
+authority-id: canonical
+series: 16
+plugs:
+  x11:
+    allow-auto-connection: true
+slots:
+  network:
+    allow-installation:
+      slot-snap-type:
+        - core
+        - gadget
+`
+	if string(b) != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, b)
+	}
+}
+
+func TestEncodeHeadersNonString(t *testing.T) {
+	for _, tc := range []struct {
+		headers map[string]interface{}
+		err     string
+	}{
+		{map[string]interface{}{"type": "base-declaration", "revision": 2}, "header revision: unsupported header value 2 of type int, expected a string"},
+		{map[string]interface{}{"plugs": map[string]interface{}{
+			"x11": map[string]interface{}{"allow-auto-connection": true},
+		}}, "header plugs: unsupported header value true of type bool, expected a string"},
+	} {
+		_, err := encodeHeaders(tc.headers)
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("expected %q, got %v", tc.err, err)
+		}
+	}
+}
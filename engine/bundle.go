@@ -0,0 +1,514 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+)
+
+// version is the engine version, set at build time with
+// -ldflags "-X main.version=..."
+var version = "devel"
+
+// snapdVersion returns the version of the snapd module the engine was
+// built with.
+func snapdVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range bi.Deps {
+		if dep.Path != "github.com/snapcore/snapd" {
+			continue
+		}
+		if dep.Replace != nil {
+			return dep.Replace.Path + " " + dep.Replace.Version
+		}
+		return dep.Version
+	}
+	return "unknown"
+}
+
+// A bundle is a tarball with everything needed to re-run a simulation
+// offline:
+//
+//	bundle.json  the op, its parameters and the versions
+//	output       the output of the op
+//	files/       the other input files, e.g. a base-declaration
+//	snaps/       the snap directories and .snap files, the op runs there
+type bundleMeta struct {
+	Op     string                 `json:"op"`
+	Params map[string]interface{} `json:"params"`
+
+	EngineVersion string `json:"engine-version"`
+	SnapdVersion  string `json:"snapd-version"`
+}
+
+// bundleSnapKeys are the op parameters naming snap inputs, as single
+// names or lists.
+var bundleSnapKeys = []string{"target-snap", "snap", "snaps"}
+
+// bundleRefKeys are the op parameters of the form <snap>:<plug or slot>.
+var bundleRefKeys = []string{"plug", "slot"}
+
+// bundleFileKeys are the op parameters naming other input files, as
+// single paths or lists.
+var bundleFileKeys = []string{"base-declaration", "validation-sets", "proposed-plugs", "proposed-slots"}
+
+// checkOffline checks that the op parameters do not point to a store,
+// as bundles are replayed offline.
+func checkOffline(params map[string]interface{}) error {
+	isURL := func(v interface{}) bool {
+		s, ok := v.(string)
+		return ok && (strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://"))
+	}
+	for k, v := range params {
+		if k == "store-url" {
+			return fmt.Errorf("cannot bundle parameter %s, bundles are replayed offline", k)
+		}
+		elems, ok := v.([]interface{})
+		if !ok {
+			elems = []interface{}{v}
+		}
+		for _, elem := range elems {
+			if isURL(elem) {
+				return fmt.Errorf("cannot bundle URL %v of parameter %s, bundles are replayed offline", elem, k)
+			}
+		}
+	}
+	return nil
+}
+
+// runOp runs an engine op in dir, capturing its output.
+func runOp(dir, op string, params map[string]interface{}) ([]byte, error) {
+	b, err := json.Marshal(params)
+	noerror(err)
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(exe, op, string(b))
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot run %s: %v: %s", op, err, strings.TrimSpace(stderr.String()))
+	}
+	return stripAppArmorStatus(out), nil
+}
+
+// stripAppArmorStatus strips the AppArmor status line that snapd code
+// can print before the output of an op, as ops/engine.py does.
+func stripAppArmorStatus(out []byte) []byte {
+	if !bytes.HasPrefix(out, []byte("AppArmor status:")) {
+		return out
+	}
+	return out[bytes.IndexByte(out, '\n')+1:]
+}
+
+// mapParam applies f to the string or list of strings parameter key.
+func mapParam(params map[string]interface{}, key string, f func(string) (string, error)) error {
+	switch v := params[key].(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		mapped, err := f(v)
+		if err != nil {
+			return err
+		}
+		params[key] = mapped
+	case []interface{}:
+		for i, elem := range v {
+			s, ok := elem.(string)
+			if !ok {
+				return fmt.Errorf("invalid %s parameter", key)
+			}
+			mapped, err := f(s)
+			if err != nil {
+				return err
+			}
+			v[i] = mapped
+		}
+	case nil:
+	default:
+		return fmt.Errorf("invalid %s parameter", key)
+	}
+	return nil
+}
+
+// bundleWriter writes the entries of a bundle tarball.
+type bundleWriter struct {
+	tw *tar.Writer
+	// added maps bundle paths to the original ones
+	added map[string]string
+}
+
+func (bw *bundleWriter) writeFile(name string, b []byte) error {
+	hdr := &tar.Header{
+		Name: name,
+		Mode: 0644,
+		Size: int64(len(b)),
+	}
+	if err := bw.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := bw.tw.Write(b)
+	return err
+}
+
+// add adds the file or directory at path as name in the bundle, links
+// e.g. into the fetch cache are followed. Adding the same path twice is
+// fine, different paths with the same name are not.
+func (bw *bundleWriter) add(path, name string) error {
+	if orig, ok := bw.added[name]; ok {
+		if orig != path {
+			return fmt.Errorf("cannot bundle both %s and %s as %s", orig, path, name)
+		}
+		return nil
+	}
+	bw.added[name] = path
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return bw.writeFile(name, b)
+	}
+	fis, err := ioutil.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, fi := range fis {
+		if err := bw.add(filepath.Join(path, fi.Name()), name+"/"+fi.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportBundle runs the op and writes the bundle with its inputs and
+// output to fn. It returns the output.
+func exportBundle(fn, op string, params map[string]interface{}) ([]byte, error) {
+	if err := checkOffline(params); err != nil {
+		return nil, err
+	}
+	// to compare the output of replays
+	params["deterministic"] = true
+
+	// the op is run as replays run it, from a staged bundle with the
+	// rewritten parameters, so that any paths in the output match
+	b, err := json.Marshal(params)
+	noerror(err)
+	var staged map[string]interface{}
+	noerror(json.Unmarshal(b, &staged))
+	dir, err := ioutil.TempDir("", "ifacetool-export")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	stagedFn := filepath.Join(dir, "bundle.tar.gz")
+	if err := writeBundle(stagedFn, op, staged, nil); err != nil {
+		return nil, err
+	}
+	meta, err := importBundle(stagedFn, filepath.Join(dir, "bundle"))
+	if err != nil {
+		return nil, err
+	}
+	out, err := runOp(filepath.Join(dir, "bundle", "snaps"), meta.Op, meta.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeBundle(fn, op, params, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// writeBundle writes the bundle for the op with its inputs and output to
+// fn, the parameters are rewritten to point into the bundle.
+func writeBundle(fn, op string, params map[string]interface{}, out []byte) error {
+	f, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gzw := gzip.NewWriter(f)
+	bw := &bundleWriter{
+		tw:    tar.NewWriter(gzw),
+		added: make(map[string]string),
+	}
+
+	addSnap := func(name string) (string, error) {
+		base := filepath.Base(name)
+		if err := bw.add(name, "snaps/"+base); err != nil {
+			return "", err
+		}
+		if isSnapFile(name) {
+			// the snap directory with the rules, if any
+			if _, dir := resolveRef(name); dir != "" {
+				if err := bw.add(dir, "snaps/"+filepath.Base(dir)); err != nil {
+					return "", err
+				}
+			}
+		}
+		return base, nil
+	}
+	for _, key := range bundleSnapKeys {
+		if err := mapParam(params, key, addSnap); err != nil {
+			return err
+		}
+	}
+	for _, key := range bundleRefKeys {
+		err := mapParam(params, key, func(ref string) (string, error) {
+			parts := strings.SplitN(ref, ":", 2)
+			if len(parts) != 2 {
+				return ref, nil
+			}
+			base, err := addSnap(parts[0])
+			if err != nil {
+				return "", err
+			}
+			return base + ":" + parts[1], nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	n := 0
+	for _, key := range bundleFileKeys {
+		err := mapParam(params, key, func(path string) (string, error) {
+			// numbered as they can have the same names
+			n++
+			name := fmt.Sprintf("files/%d-%s", n, filepath.Base(path))
+			if err := bw.add(path, name); err != nil {
+				return "", err
+			}
+			// relative to snaps/
+			return "../" + name, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	meta := bundleMeta{
+		Op:            op,
+		Params:        params,
+		EngineVersion: version,
+		SnapdVersion:  snapdVersion(),
+	}
+	b, err := json.MarshalIndent(&meta, "", "  ")
+	noerror(err)
+	if err := bw.writeFile("bundle.json", append(b, '\n')); err != nil {
+		return err
+	}
+	if err := bw.writeFile("output", out); err != nil {
+		return err
+	}
+	if err := bw.tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
+
+// importBundle extracts the bundle into dir and returns its metadata.
+func importBundle(fn, dir string) (*bundleMeta, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read bundle %s: %v", fn, err)
+	}
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read bundle %s: %v", fn, err)
+		}
+		name := filepath.Clean(hdr.Name)
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("invalid bundle entry %q", hdr.Name)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		dest := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(dest, b, 0644); err != nil {
+			return nil, err
+		}
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "bundle.json"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle %s: %v", fn, err)
+	}
+	var meta bundleMeta
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return nil, fmt.Errorf("invalid bundle %s: %v", fn, err)
+	}
+	if err := checkOffline(meta.Params); err != nil {
+		return nil, fmt.Errorf("invalid bundle %s: %v", fn, err)
+	}
+	// the snaps directory is created even if there are no snaps
+	if err := os.MkdirAll(filepath.Join(dir, "snaps"), 0755); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+type replayResult struct {
+	Bundle *bundleMeta `json:"bundle"`
+
+	EngineVersion string `json:"engine-version"`
+	SnapdVersion  string `json:"snapd-version"`
+
+	// Recorded is the output in the bundle, Output the one from the
+	// replay
+	Recorded  string `json:"recorded"`
+	Output    string `json:"output"`
+	Identical bool   `json:"identical"`
+}
+
+// Operations
+
+func versionOp(param *json.RawMessage) error {
+	b, err := json.Marshal(map[string]string{
+		"engine-version": version,
+		"snapd-version":  snapdVersion(),
+	})
+	noerror(err)
+	fmt.Println(string(b))
+	return nil
+}
+
+func exportOp(param *json.RawMessage) error {
+	var params struct {
+		// Op and Params are the op to run and export with its
+		// parameters
+		Op     string                 `json:"op"`
+		Params map[string]interface{} `json:"params"`
+		// Output is the path of the bundle to write
+		Output string `json:"output"`
+	}
+	if err := json.Unmarshal([]byte(*param), &params); err != nil {
+		return err
+	}
+	switch params.Op {
+	case "export", "import", "replay", "fetch", "fetch-decls", "mock-store":
+		return fmt.Errorf("cannot export op %s", params.Op)
+	}
+	if params.Params == nil {
+		params.Params = make(map[string]interface{})
+	}
+	out, err := exportBundle(params.Output, params.Op, params.Params)
+	if err != nil {
+		return err
+	}
+	// the output of the op as recorded
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+func importOp(param *json.RawMessage) error {
+	var params struct {
+		Bundle string `json:"bundle"`
+		// Dir is the directory to extract the bundle into
+		Dir string `json:"dir"`
+	}
+	if err := json.Unmarshal([]byte(*param), &params); err != nil {
+		return err
+	}
+	meta, err := importBundle(params.Bundle, params.Dir)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(meta)
+	noerror(err)
+	fmt.Println(string(b))
+	return nil
+}
+
+func replayOp(param *json.RawMessage) error {
+	var params struct {
+		Bundle string `json:"bundle"`
+	}
+	if err := json.Unmarshal([]byte(*param), &params); err != nil {
+		return err
+	}
+	dir, err := ioutil.TempDir("", "ifacetool-replay")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	meta, err := importBundle(params.Bundle, dir)
+	if err != nil {
+		return err
+	}
+	recorded, err := ioutil.ReadFile(filepath.Join(dir, "output"))
+	if err != nil {
+		return fmt.Errorf("invalid bundle %s: %v", params.Bundle, err)
+	}
+	out, err := runOp(filepath.Join(dir, "snaps"), meta.Op, meta.Params)
+	if err != nil {
+		return err
+	}
+	res := replayResult{
+		Bundle:        meta,
+		EngineVersion: version,
+		SnapdVersion:  snapdVersion(),
+		Recorded:      string(recorded),
+		Output:        string(out),
+		Identical:     bytes.Equal(recorded, out),
+	}
+	b, err := json.Marshal(&res)
+	noerror(err)
+	fmt.Println(string(b))
+	return nil
+}
@@ -0,0 +1,253 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, fn, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(fn, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteBundleMapsPaths(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTestFile(t, "foo/snap.yaml", "name: foo\n")
+	writeTestFile(t, "foo/plugs.json", "{}\n")
+	writeTestFile(t, "sub/bar/snap.yaml", "name: bar\n")
+	writeTestFile(t, "decl.txt", "type: base-declaration\n")
+	writeTestFile(t, "a/vs.assert", "one\n")
+	writeTestFile(t, "b/vs.assert", "two\n")
+
+	params := map[string]interface{}{
+		"target-snap":      "foo",
+		"snaps":            []interface{}{"sub/bar", "foo"},
+		"plug":             "sub/bar:x11",
+		"base-declaration": "decl.txt",
+		"validation-sets":  []interface{}{"a/vs.assert", "b/vs.assert"},
+		"architecture":     "arm64",
+	}
+	if err := writeBundle("bundle.tar.gz", "suggest", params, []byte(`{"current": {}}`+"\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := importBundle("bundle.tar.gz", "imported")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Op != "suggest" || meta.EngineVersion != version {
+		t.Errorf("unexpected bundle metadata %+v", meta)
+	}
+	want := map[string]interface{}{
+		"target-snap":      "foo",
+		"snaps":            []interface{}{"bar", "foo"},
+		"plug":             "bar:x11",
+		"base-declaration": "../files/1-decl.txt",
+		"validation-sets":  []interface{}{"../files/2-vs.assert", "../files/3-vs.assert"},
+		"architecture":     "arm64",
+	}
+	if !reflect.DeepEqual(meta.Params, want) {
+		t.Errorf("expected params %v, got %v", want, meta.Params)
+	}
+
+	for fn, content := range map[string]string{
+		"output":               `{"current": {}}` + "\n",
+		"snaps/foo/snap.yaml":  "name: foo\n",
+		"snaps/foo/plugs.json": "{}\n",
+		"snaps/bar/snap.yaml":  "name: bar\n",
+		"files/1-decl.txt":     "type: base-declaration\n",
+		"files/2-vs.assert":    "one\n",
+		"files/3-vs.assert":    "two\n",
+	} {
+		if got := readFile(t, filepath.Join("imported", fn)); got != content {
+			t.Errorf("%s: expected %q, got %q", fn, content, got)
+		}
+	}
+	// the parameters work relative to snaps/
+	snapsDir := filepath.Join("imported", "snaps")
+	if _, err := os.Stat(filepath.Join(snapsDir, "../files/1-decl.txt")); err != nil {
+		t.Errorf("base-declaration not found from snaps/: %v", err)
+	}
+}
+
+func TestWriteBundleFollowsLinks(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTestFile(t, "cache/meta/foo-id/1/snap.yaml", "name: foo\n")
+	if err := os.Mkdir("foo", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(mustAbs(t, "cache/meta/foo-id/1/snap.yaml"), "foo/snap.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	params := map[string]interface{}{"snaps": []interface{}{"foo"}}
+	if err := writeBundle("bundle.tar.gz", "check-meta", params, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := importBundle("bundle.tar.gz", "imported"); err != nil {
+		t.Fatal(err)
+	}
+	fn := filepath.Join("imported", "snaps", "foo", "snap.yaml")
+	fi, err := os.Lstat(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.Mode().IsRegular() {
+		t.Errorf("expected a regular file, got %v", fi.Mode())
+	}
+	if got := readFile(t, fn); got != "name: foo\n" {
+		t.Errorf("unexpected snap.yaml %q", got)
+	}
+}
+
+func TestWriteBundleNameClash(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTestFile(t, "a/foo/snap.yaml", "name: foo\n")
+	writeTestFile(t, "b/foo/snap.yaml", "name: foo\n")
+	params := map[string]interface{}{"snaps": []interface{}{"a/foo", "b/foo"}}
+	err := writeBundle("bundle.tar.gz", "system-graph", params, nil)
+	const wantErr = "cannot bundle both a/foo and b/foo as snaps/foo"
+	if err == nil || err.Error() != wantErr {
+		t.Errorf("expected %q, got %v", wantErr, err)
+	}
+}
+
+// writeTarball writes a gzipped tarball with the given entries.
+func writeTarball(t *testing.T, fn string, hdrs []*tar.Header, contents []string) {
+	t.Helper()
+	f, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+	for i, hdr := range hdrs {
+		hdr.Size = int64(len(contents[i]))
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(contents[i])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportBundleRejectsTraversal(t *testing.T) {
+	chdir(t, t.TempDir())
+	for _, name := range []string{"../evil", "snaps/../../evil", "/tmp/evil"} {
+		writeTarball(t, "bundle.tar.gz", []*tar.Header{
+			{Name: name, Mode: 0644, Typeflag: tar.TypeReg},
+		}, []string{"evil\n"})
+		_, err := importBundle("bundle.tar.gz", "imported")
+		if err == nil || !strings.HasPrefix(err.Error(), "invalid bundle entry") {
+			t.Errorf("%s: expected invalid entry error, got %v", name, err)
+		}
+	}
+	if _, err := os.Stat("evil"); !os.IsNotExist(err) {
+		t.Errorf("entry was extracted outside of the directory: %v", err)
+	}
+}
+
+func TestImportBundleSkipsLinks(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTarball(t, "bundle.tar.gz", []*tar.Header{
+		{Name: "bundle.json", Mode: 0644, Typeflag: tar.TypeReg},
+		{Name: "snaps/foo", Linkname: "/etc", Typeflag: tar.TypeSymlink},
+	}, []string{`{"op": "check-meta", "params": {}}`, ""})
+	meta, err := importBundle("bundle.tar.gz", "imported")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Op != "check-meta" {
+		t.Errorf("unexpected op %s", meta.Op)
+	}
+	if _, err := os.Lstat(filepath.Join("imported", "snaps", "foo")); !os.IsNotExist(err) {
+		t.Errorf("expected the link to be skipped, got %v", err)
+	}
+}
+
+func TestImportBundleChecksOffline(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTarball(t, "bundle.tar.gz", []*tar.Header{
+		{Name: "bundle.json", Mode: 0644, Typeflag: tar.TypeReg},
+	}, []string{`{"op": "check-meta", "params": {"store-url": "http://localhost:8000/"}}`})
+	_, err := importBundle("bundle.tar.gz", "imported")
+	if err == nil || !strings.Contains(err.Error(), "bundles are replayed offline") {
+		t.Errorf("expected offline error, got %v", err)
+	}
+}
+
+func TestCheckOffline(t *testing.T) {
+	for _, tc := range []struct {
+		params map[string]interface{}
+		err    string
+	}{
+		{map[string]interface{}{"snaps": []interface{}{"foo"}, "store": "my-store", "classic": true}, ""},
+		{map[string]interface{}{"store-url": ""}, "cannot bundle parameter store-url, bundles are replayed offline"},
+		{map[string]interface{}{"snap": "https://example.com/foo.snap"}, "cannot bundle URL https://example.com/foo.snap of parameter snap, bundles are replayed offline"},
+		{map[string]interface{}{"snaps": []interface{}{"foo", "http://example.com/bar"}}, "cannot bundle URL http://example.com/bar of parameter snaps, bundles are replayed offline"},
+	} {
+		err := checkOffline(tc.params)
+		if tc.err == "" {
+			if err != nil {
+				t.Errorf("%v: unexpected error %v", tc.params, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("%v: expected %q, got %v", tc.params, tc.err, err)
+		}
+	}
+}
+
+func TestStripAppArmorStatus(t *testing.T) {
+	for _, tc := range []struct {
+		out, stripped string
+	}{
+		{"{}\n", "{}\n"},
+		{"AppArmor status: apparmor not enabled\n{}\n", "{}\n"},
+		{"AppArmor status: apparmor not enabled\n", ""},
+		{"{\"status\": \"AppArmor status: x\"}\n", "{\"status\": \"AppArmor status: x\"}\n"},
+		{"", ""},
+	} {
+		if got := string(stripAppArmorStatus([]byte(tc.out))); got != tc.stripped {
+			t.Errorf("%q: expected %q, got %q", tc.out, tc.stripped, got)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/snapcore/snapd/osutil"
+)
+
+// fetchCache is a shared on-disk cache of what fetch gets from the store.
+// Its entries are directories keyed by:
+//
+//	refs/<snap-name>: .snap.json
+//	meta/<snap-id>/<revision>: snap.yaml
+//	decls/<snap-id>/<decl-revision>: plugs.json, slots.json
+//
+// Entries are written once and never modified, the files of the snap
+// directories are then symlinks into the cache.
+type fetchCache struct {
+	dir string
+}
+
+// newFetchCache returns the cache at dir, or nil if dir is empty.
+func newFetchCache(dir string) (*fetchCache, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &fetchCache{dir: dir}, nil
+}
+
+func (fc *fetchCache) refEntry(name string) string {
+	return filepath.Join(fc.dir, "refs", name)
+}
+
+func (fc *fetchCache) metaEntry(snapID string, revision int) string {
+	return filepath.Join(fc.dir, "meta", snapID, strconv.Itoa(revision))
+}
+
+func (fc *fetchCache) declEntry(snapID string, revision int) string {
+	return filepath.Join(fc.dir, "decls", snapID, strconv.Itoa(revision))
+}
+
+// has returns whether the cache entry exists.
+func (fc *fetchCache) has(entry string) bool {
+	return osutil.IsDirectory(entry)
+}
+
+// put writes a cache entry with the given files atomically, unless it
+// exists already. The files are read-only so that tentative
+// modifications in the snap directories don't go through into the
+// cache.
+func (fc *fetchCache) put(entry string, files map[string][]byte) error {
+	if fc.has(entry) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(entry), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(filepath.Dir(entry), ".tmp-")
+	if err != nil {
+		return err
+	}
+	for what, b := range files {
+		if err := ioutil.WriteFile(filepath.Join(tmp, what), b, 0444); err != nil {
+			os.RemoveAll(tmp)
+			return err
+		}
+	}
+	if err := os.Chmod(tmp, 0755); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, entry); err != nil {
+		os.RemoveAll(tmp)
+		if fc.has(entry) {
+			// put concurrently by someone else
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// link makes the given files of the snap directory symlinks to the ones
+// of the cache entry, files missing from the entry are removed.
+func (fc *fetchCache) link(name, entry string, what ...string) error {
+	absEntry, err := filepath.Abs(entry)
+	if err != nil {
+		return err
+	}
+	for _, w := range what {
+		dest := filepath.Join(name, w)
+		if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		src := filepath.Join(absEntry, w)
+		if !osutil.FileExists(src) {
+			continue
+		}
+		if err := os.Symlink(src, dest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// chdir changes to dir for the duration of the test, snap directories
+// are relative to the current directory.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	old, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(old)
+	})
+}
+
+func readFile(t *testing.T, fn string) string {
+	t.Helper()
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestFetchCacheNone(t *testing.T) {
+	fc, err := newFetchCache("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc != nil {
+		t.Errorf("expected no cache, got %v", fc)
+	}
+}
+
+func TestFetchCacheLayout(t *testing.T) {
+	dir := t.TempDir()
+	fc, err := newFetchCache(filepath.Join(dir, "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		entry string
+		want  string
+	}{
+		{fc.refEntry("foo"), "cache/refs/foo"},
+		{fc.metaEntry("foo-id", 12), "cache/meta/foo-id/12"},
+		{fc.declEntry("foo-id", 0), "cache/decls/foo-id/0"},
+	} {
+		if want := filepath.Join(dir, tc.want); tc.entry != want {
+			t.Errorf("expected entry %s, got %s", want, tc.entry)
+		}
+	}
+}
+
+func TestFetchCachePut(t *testing.T) {
+	fc, err := newFetchCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := fc.metaEntry("foo-id", 1)
+	if fc.has(entry) {
+		t.Fatalf("unexpected entry %s", entry)
+	}
+	err = fc.put(entry, map[string][]byte{"snap.yaml": []byte("name: foo\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fc.has(entry) {
+		t.Fatalf("missing entry %s", entry)
+	}
+	fn := filepath.Join(entry, "snap.yaml")
+	if got := readFile(t, fn); got != "name: foo\n" {
+		t.Errorf("unexpected snap.yaml %q", got)
+	}
+	fi, err := os.Stat(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0444 {
+		t.Errorf("expected read-only snap.yaml, got %v", fi.Mode().Perm())
+	}
+
+	// entries are never modified
+	err = fc.put(entry, map[string][]byte{"snap.yaml": []byte("name: bar\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, fn); got != "name: foo\n" {
+		t.Errorf("entry was modified, snap.yaml %q", got)
+	}
+
+	// no temporary directories are left behind
+	fis, err := ioutil.ReadDir(filepath.Dir(entry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fis) != 1 || fis[0].Name() != "1" {
+		var names []string
+		for _, fi := range fis {
+			names = append(names, fi.Name())
+		}
+		t.Errorf("unexpected cache contents %v", names)
+	}
+}
+
+func TestFetchCacheLink(t *testing.T) {
+	chdir(t, t.TempDir())
+	fc, err := newFetchCache("cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir("foo", 0755); err != nil {
+		t.Fatal(err)
+	}
+	// stale files in the snap directory
+	for _, what := range []string{"plugs.json", "slots.json"} {
+		if err := ioutil.WriteFile(filepath.Join("foo", what), []byte("{}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entry := fc.declEntry("foo-id", 3)
+	err = fc.put(entry, map[string][]byte{"plugs.json": []byte(`{"x11": {}}` + "\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fc.link("foo", entry, "plugs.json", "slots.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	target, err := os.Readlink(filepath.Join("foo", "plugs.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !filepath.IsAbs(target) || target != filepath.Join(mustAbs(t, entry), "plugs.json") {
+		t.Errorf("unexpected link target %s", target)
+	}
+	if got := readFile(t, filepath.Join("foo", "plugs.json")); got != `{"x11": {}}`+"\n" {
+		t.Errorf("unexpected plugs.json %q", got)
+	}
+	// missing from the entry
+	if _, err := os.Lstat(filepath.Join("foo", "slots.json")); !os.IsNotExist(err) {
+		t.Errorf("expected slots.json to be removed, got %v", err)
+	}
+}
+
+func TestWriteSnapDirFileReplacesLink(t *testing.T) {
+	chdir(t, t.TempDir())
+	fc, err := newFetchCache("cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir("foo", 0755); err != nil {
+		t.Fatal(err)
+	}
+	entry := fc.metaEntry("foo-id", 1)
+	if err := fc.put(entry, map[string][]byte{"snap.yaml": []byte("name: foo\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fc.link("foo", entry, "snap.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeSnapDirFile("foo", "snap.yaml", []byte("name: foo\nversion: 2\n")); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Lstat(filepath.Join("foo", "snap.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		t.Errorf("snap.yaml is still a link")
+	}
+	if got := readFile(t, filepath.Join(entry, "snap.yaml")); got != "name: foo\n" {
+		t.Errorf("write went through into the cache, snap.yaml %q", got)
+	}
+}
+
+func mustAbs(t *testing.T, path string) string {
+	t.Helper()
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return abs
+}
@@ -0,0 +1,179 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/builtin"
+)
+
+type interfacesParams struct {
+	// BaseDeclaration is the path of a base-declaration to use instead
+	// of the builtin one
+	BaseDeclaration string `json:"base-declaration"`
+	// Interface restricts the catalogue to one interface
+	Interface string `json:"interface"`
+}
+
+// interfaceEntry describes an interface known to snapd.
+type interfaceEntry struct {
+	Name    string `json:"name"`
+	Summary string `json:"summary"`
+	DocURL  string `json:"doc-url,omitempty"`
+
+	// ImplicitOnCore and ImplicitOnClassic tell whether the system
+	// snap gets an implicit slot for the interface
+	ImplicitOnCore    bool `json:"implicit-on-core"`
+	ImplicitOnClassic bool `json:"implicit-on-classic"`
+
+	// SuperPrivileged is set if installing a snap with a plug for the
+	// interface is denied by the base-declaration
+	SuperPrivileged bool `json:"super-privileged"`
+
+	// Policy summarizes the base-declaration constraints per side and
+	// key: true, false or constrained
+	Policy map[string]map[string]string `json:"policy"`
+	// PlugRule and SlotRule are the base-declaration rules
+	PlugRule interface{} `json:"plug-rule,omitempty"`
+	SlotRule interface{} `json:"slot-rule,omitempty"`
+
+	// Attributes are the attributes referred to by the
+	// base-declaration constraints, snapd has no attributes schema
+	Attributes []string `json:"attributes,omitempty"`
+}
+
+type interfacesResult struct {
+	Interfaces []*interfaceEntry `json:"interfaces"`
+}
+
+// summarizeConstraints summarizes constraints as true, false or
+// constrained.
+func summarizeConstraints(constraints interface{}) string {
+	if s, ok := constraints.(string); ok {
+		return s
+	}
+	return "constrained"
+}
+
+// constraintsAttributes collects the attribute names referred to by
+// plug-attributes and slot-attributes constraints.
+func constraintsAttributes(v interface{}, attrs map[string]interface{}) {
+	switch x := v.(type) {
+	case []interface{}:
+		for _, elem := range x {
+			constraintsAttributes(elem, attrs)
+		}
+	case map[string]interface{}:
+		for k, elem := range x {
+			if k == "plug-attributes" || k == "slot-attributes" {
+				if m, ok := elem.(map[string]interface{}); ok {
+					for attr := range m {
+						attrs[attr] = true
+					}
+				}
+				continue
+			}
+			constraintsAttributes(elem, attrs)
+		}
+	}
+}
+
+func interfacesCatalogue(params *interfacesParams) (*interfacesResult, error) {
+	if params.BaseDeclaration != "" {
+		if err := useBaseDeclaration(params.BaseDeclaration); err != nil {
+			return nil, err
+		}
+	}
+	headers := asserts.BuiltinBaseDeclaration().Headers()
+	rules := func(side string) map[string]interface{} {
+		m, _ := headers[side].(map[string]interface{})
+		return m
+	}
+
+	res := &interfacesResult{}
+	found := false
+	for _, iface := range builtin.Interfaces() {
+		name := iface.Name()
+		if params.Interface != "" && name != params.Interface {
+			continue
+		}
+		found = true
+		si := interfaces.StaticInfoOf(iface)
+		entry := &interfaceEntry{
+			Name:              name,
+			Summary:           si.Summary,
+			DocURL:            si.DocURL,
+			ImplicitOnCore:    si.ImplicitOnCore,
+			ImplicitOnClassic: si.ImplicitOnClassic,
+			Policy:            make(map[string]map[string]string),
+			PlugRule:          rules("plugs")[name],
+			SlotRule:          rules("slots")[name],
+		}
+		attrs := make(map[string]interface{})
+		for side, rule := range map[string]interface{}{
+			"plugs": entry.PlugRule,
+			"slots": entry.SlotRule,
+		} {
+			m, ok := rule.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			policy := make(map[string]string)
+			for _, key := range ruleKeys {
+				if constraints, ok := m[key]; ok {
+					policy[key] = summarizeConstraints(constraints)
+				}
+			}
+			entry.Policy[side] = policy
+			constraintsAttributes(m, attrs)
+		}
+		plugPolicy := entry.Policy["plugs"]
+		entry.SuperPrivileged = plugPolicy["allow-installation"] == "false" || plugPolicy["deny-installation"] == "true"
+		entry.Attributes = sortedHeaderKeys(attrs)
+		res.Interfaces = append(res.Interfaces, entry)
+	}
+	if !found && params.Interface != "" {
+		return nil, fmt.Errorf("unknown interface: %s", params.Interface)
+	}
+	return res, nil
+}
+
+// Operations
+
+func interfacesOp(param *json.RawMessage) error {
+	var params interfacesParams
+	if err := json.Unmarshal([]byte(*param), &params); err != nil {
+		return err
+	}
+
+	res, err := interfacesCatalogue(&params)
+	if err != nil {
+		return outputError("json", err)
+	}
+
+	b, err := json.Marshal(res)
+	noerror(err)
+	fmt.Println(string(b))
+	return nil
+}
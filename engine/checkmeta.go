@@ -0,0 +1,205 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/snap"
+)
+
+// finding severities
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+type checkMetaParams struct {
+	Snaps []string `json:"snaps"`
+}
+
+// metaFinding is an issue found in the snap.yaml of a snap.
+type metaFinding struct {
+	Severity string `json:"severity"`
+	Plug     string `json:"plug,omitempty"`
+	Slot     string `json:"slot,omitempty"`
+	// Attribute is the attribute the message is about, if it could
+	// be determined
+	Attribute string `json:"attribute,omitempty"`
+	Message   string `json:"message"`
+}
+
+type metaCheck struct {
+	Snap     string         `json:"snap"`
+	Findings []*metaFinding `json:"findings"`
+}
+
+type checkMetaResult struct {
+	Snaps []*metaCheck `json:"snaps"`
+}
+
+// findAttribute returns the attribute mentioned in the message, if any.
+func findAttribute(attrs map[string]interface{}, msg string) string {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	// prefer the longest name, e.g. read-only over read, ties are broken
+	// by name to be deterministic
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		if strings.Contains(msg, name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// checkSnapMeta checks the snap.yaml in a snap directory.
+func checkSnapMeta(name string, ifaces map[string]interfaces.Interface) *metaCheck {
+	mc := &metaCheck{Snap: name}
+	add := func(f *metaFinding) {
+		mc.Findings = append(mc.Findings, f)
+	}
+
+	info, err := readSnapInfo(name)
+	if err != nil {
+		add(&metaFinding{Severity: severityError, Message: err.Error()})
+		return mc
+	}
+	mc.Snap = info.SnapName()
+	if err := snap.Validate(info); err != nil {
+		add(&metaFinding{Severity: severityError, Message: err.Error()})
+	}
+
+	plugNames := make([]string, 0, len(info.Plugs))
+	for plugName := range info.Plugs {
+		plugNames = append(plugNames, plugName)
+	}
+	sort.Strings(plugNames)
+	for _, plugName := range plugNames {
+		plug := info.Plugs[plugName]
+		iface, ok := ifaces[plug.Interface]
+		if !ok {
+			add(&metaFinding{
+				Severity: severityError,
+				Plug:     plugName,
+				Message:  fmt.Sprintf("unknown interface %q", plug.Interface),
+			})
+			continue
+		}
+		// the sanitizers can set attributes, look them up in the
+		// attributes as they were
+		attrs := make(map[string]interface{}, len(plug.Attrs))
+		for k, v := range plug.Attrs {
+			attrs[k] = v
+		}
+		if err := interfaces.BeforePreparePlug(iface, plug); err != nil {
+			add(&metaFinding{
+				Severity:  severityError,
+				Plug:      plugName,
+				Attribute: findAttribute(attrs, err.Error()),
+				Message:   err.Error(),
+			})
+		}
+		if len(plug.Apps) == 0 && len(plug.Hooks) == 0 && plug.Interface != "content" {
+			add(&metaFinding{
+				Severity: severityWarning,
+				Plug:     plugName,
+				Message:  "plug is not bound to any app or hook",
+			})
+		}
+	}
+
+	slotNames := make([]string, 0, len(info.Slots))
+	for slotName := range info.Slots {
+		slotNames = append(slotNames, slotName)
+	}
+	sort.Strings(slotNames)
+	for _, slotName := range slotNames {
+		slot := info.Slots[slotName]
+		iface, ok := ifaces[slot.Interface]
+		if !ok {
+			add(&metaFinding{
+				Severity: severityError,
+				Slot:     slotName,
+				Message:  fmt.Sprintf("unknown interface %q", slot.Interface),
+			})
+			continue
+		}
+		attrs := make(map[string]interface{}, len(slot.Attrs))
+		for k, v := range slot.Attrs {
+			attrs[k] = v
+		}
+		if err := interfaces.BeforePrepareSlot(iface, slot); err != nil {
+			add(&metaFinding{
+				Severity:  severityError,
+				Slot:      slotName,
+				Attribute: findAttribute(attrs, err.Error()),
+				Message:   err.Error(),
+			})
+		}
+		// content slots of data-only snaps need no app
+		if len(slot.Apps) == 0 && len(slot.Hooks) == 0 && slot.Interface != "content" {
+			add(&metaFinding{
+				Severity: severityWarning,
+				Slot:     slotName,
+				Message:  "slot is not bound to any app or hook",
+			})
+		}
+	}
+	return mc
+}
+
+func checkMeta(params *checkMetaParams) *checkMetaResult {
+	ifaces := make(map[string]interfaces.Interface)
+	for _, iface := range builtin.Interfaces() {
+		ifaces[iface.Name()] = iface
+	}
+	res := &checkMetaResult{}
+	for _, name := range params.Snaps {
+		res.Snaps = append(res.Snaps, checkSnapMeta(name, ifaces))
+	}
+	return res
+}
+
+// Operations
+
+func checkMetaOp(param *json.RawMessage) error {
+	var params checkMetaParams
+	if err := json.Unmarshal([]byte(*param), &params); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(checkMeta(&params))
+	noerror(err)
+	fmt.Println(string(b))
+	return nil
+}
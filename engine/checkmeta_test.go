@@ -0,0 +1,48 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import "testing"
+
+func TestFindAttribute(t *testing.T) {
+	attrs := map[string]interface{}{
+		"read":      nil,
+		"read-only": nil,
+		"write":     nil,
+	}
+	for msg, want := range map[string]string{
+		`cannot add "read-only" path "/x"`: "read-only",
+		`cannot add "read" path "/x"`:      "read",
+		`"write" must be a list`:           "write",
+		`interface is not allowed`:         "",
+	} {
+		if got := findAttribute(attrs, msg); got != want {
+			t.Errorf("%q: expected %q, got %q", msg, want, got)
+		}
+	}
+
+	// ties are broken by name
+	attrs = map[string]interface{}{"ab": nil, "ba": nil}
+	for i := 0; i < 10; i++ {
+		if got := findAttribute(attrs, "abba"); got != "ab" {
+			t.Fatalf("expected ab, got %q", got)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store/tooling"
+)
+
+// closureChannel is the channel the dependencies are fetched from, as
+// they would be installed on devices.
+const closureChannel = "latest/stable"
+
+// snapDeps returns the snaps a snap needs in a simulation: its base
+// and the default-providers of its content plugs.
+func snapDeps(name string) ([]string, error) {
+	info, err := readSnapInfo(name)
+	if err != nil {
+		return nil, err
+	}
+	var deps []string
+	switch {
+	case info.Base == "none":
+	case info.Base != "":
+		deps = append(deps, info.Base)
+	case info.Type() == snap.TypeApp:
+		// apps without a base use core
+		deps = append(deps, "core")
+	}
+	for _, plug := range info.Plugs {
+		if plug.Interface != "content" {
+			continue
+		}
+		var provider string
+		if err := plug.Attr("default-provider", &provider); err != nil {
+			continue
+		}
+		// the provider can be given as <snap>:<slot>
+		provider = strings.SplitN(provider, ":", 2)[0]
+		if provider != "" {
+			deps = append(deps, provider)
+		}
+	}
+	return deps, nil
+}
+
+// isLocalSnapDir returns whether the snap directory is for a local snap,
+// i.e. with metadata from a local file which is recorded as revision.
+func isLocalSnapDir(name string) bool {
+	return osutil.FileExists(filepath.Join(name, "revision")) && readRevision(name, "revision") < 0
+}
+
+// fetchClosure fetches the dependencies of the fetched snaps, see
+// snapDeps, and the platform snaps, transitively. Snap directories of
+// local snaps are kept, revisions pinned by validation sets are used. It
+// writes a scenario for the first snap with the others as context snaps,
+// ready to run with auto-connections. It sets the snaps fetched as
+// dependencies and the scenario in res.
+func fetchClosure(ds *devStore, tsto *tooling.ToolingStore, snaps []fetchSnap, seen map[string]bool, params *fetchParams, res *fetchResult) ([]*fetchChange, error) {
+	var closure []string
+	var changes []*fetchChange
+	queue := make([]string, 0, len(snaps))
+	for _, fsnap := range snaps {
+		queue = append(queue, fsnap.Name)
+	}
+	var pending []fetchSnap
+	// the dependencies of the dependencies are needed
+	depParams := *params
+	depParams.Meta = true
+	addDep := func(dep string) {
+		if seen[dep] {
+			return
+		}
+		seen[dep] = true
+		closure = append(closure, dep)
+		if isLocalSnapDir(dep) {
+			queue = append(queue, dep)
+			return
+		}
+		if rev, ok := params.pinned[dep]; ok {
+			pending = append(pending, fetchSnap{
+				Name:     dep,
+				Revision: rev,
+			})
+			return
+		}
+		pending = append(pending, fetchSnap{
+			Name:    dep,
+			Channel: closureChannel,
+		})
+	}
+	for _, dep := range params.Platform {
+		addDep(dep)
+	}
+	for {
+		// local dependencies are added to the queue directly
+		for len(queue) != 0 {
+			name := queue[0]
+			queue = queue[1:]
+			deps, err := snapDeps(name)
+			if err != nil {
+				return nil, fmt.Errorf("cannot find dependencies of %s: %v", name, err)
+			}
+			for _, dep := range deps {
+				addDep(dep)
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+		fetched, err := fetchAll(ds, tsto, pending, &depParams)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, fetched...)
+		for _, fsnap := range pending {
+			queue = append(queue, fsnap.Name)
+		}
+		pending = nil
+	}
+
+	target := snaps[0].Name
+	var context []string
+	for _, fsnap := range snaps[1:] {
+		context = append(context, fsnap.Name)
+	}
+	sort.Strings(closure)
+	context = append(context, closure...)
+	scenario := map[string]interface{}{
+		"target-snap":  target,
+		"snaps":        context,
+		"architecture": params.Architecture,
+		"brand":        defaultBrand,
+		"model":        defaultModel,
+	}
+	if len(params.ValidationSets) != 0 {
+		scenario["validation-sets"] = params.ValidationSets
+	}
+	scenarioFile := target + ".scenario.json"
+	if err := writeJSON(".", scenarioFile, scenario); err != nil {
+		return nil, err
+	}
+	res.Closure = closure
+	res.Scenario = scenario
+	res.ScenarioFile = scenarioFile
+	return changes, nil
+}
@@ -0,0 +1,448 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/policy"
+	"github.com/snapcore/snapd/snap"
+)
+
+// pair verdicts
+const (
+	verdictAuto   = "auto"
+	verdictManual = "manual"
+	verdictDenied = "denied"
+)
+
+// pairVerdict is the outcome of evaluating the policy for a plug/slot pair.
+type pairVerdict struct {
+	Interface string             `json:"interface"`
+	PlugRef   interfaces.PlugRef `json:"plug"`
+	SlotRef   interfaces.SlotRef `json:"slot"`
+
+	// Verdict is one of auto, manual or denied
+	Verdict string `json:"verdict"`
+	// Reason is the error deciding the verdict, the auto-connection one
+	// for manual, the connection one for denied
+	Reason string `json:"reason,omitempty"`
+
+	AllowConnection     bool   `json:"allow-connection"`
+	ConnectError        string `json:"connect-error,omitempty"`
+	AllowAutoConnection bool   `json:"allow-auto-connection"`
+	AutoConnectError    string `json:"auto-connect-error,omitempty"`
+
+	SlotsPerPlugAny bool `json:"slots-per-plug-any,omitempty"`
+	// Candidates are, for an ambiguous plug, all the slots it is allowed
+	// to auto-connect to
+	Candidates []interfaces.SlotRef `json:"candidates,omitempty"`
+}
+
+// corpus evaluates plug/slot pairs directly with the policy over a
+// scenario whose snaps are all installed.
+type corpus struct {
+	sc *scenario
+
+	baseDecl *asserts.BaseDeclaration
+	decls    map[string]*asserts.SnapDeclaration
+	appSets  map[string]*interfaces.SnapAppSet
+	// dirs maps snap names to their snap directories
+	dirs map[string]string
+
+	// given are all the snaps in the order given, the scenario snaps
+	// are only the installed ones
+	given []string
+	// refused are the installation reports of the snaps refused in
+	// validation enforce mode, see scenario.refusal
+	refused map[string]*installation
+}
+
+// setupCorpus sets up a scenario with the given snap directories and
+// installs all of them, but the ones refused in validation enforce mode.
+func (s *oneshotSimulation) setupCorpus(params *scenarioParams, snaps []string) (*corpus, error) {
+	sc, err := s.setupScenario(params, snaps)
+	if err != nil {
+		return nil, err
+	}
+	c := &corpus{
+		sc:       sc,
+		baseDecl: asserts.BuiltinBaseDeclaration(),
+		decls:    make(map[string]*asserts.SnapDeclaration),
+		appSets:  make(map[string]*interfaces.SnapAppSet),
+		dirs:     make(map[string]string, len(sc.snaps)),
+		given:    sc.snaps,
+		refused:  make(map[string]*installation),
+	}
+	var installed []string
+	for _, name := range sc.snaps {
+		if inst := sc.refusal(name); inst != nil {
+			c.refused[name] = inst
+			continue
+		}
+		if _, err := s.installSnap(sc, name); err != nil {
+			return nil, err
+		}
+		c.dirs[sc.infos[name].SnapName()] = name
+		installed = append(installed, name)
+	}
+	sc.snaps = installed
+	for _, mocked := range sc.installed {
+		c.decls[mocked.info.SnapName()] = mocked.decl
+	}
+	return c, nil
+}
+
+// appSet returns the app set for the snap of the plug or slot, the infos
+// from the repository are used as they carry also any implicit slots.
+func (c *corpus) appSet(info *snap.Info) (*interfaces.SnapAppSet, error) {
+	if appSet := c.appSets[info.SnapName()]; appSet != nil {
+		return appSet, nil
+	}
+	appSet, err := interfaces.NewSnapAppSet(info, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.appSets[info.SnapName()] = appSet
+	return appSet, nil
+}
+
+// connectCandidate builds the policy candidate for a plug/slot pair.
+func (c *corpus) connectCandidate(plug *snap.PlugInfo, slot *snap.SlotInfo) (*policy.ConnectCandidate, error) {
+	plugAppSet, err := c.appSet(plug.Snap)
+	if err != nil {
+		return nil, err
+	}
+	slotAppSet, err := c.appSet(slot.Snap)
+	if err != nil {
+		return nil, err
+	}
+	return &policy.ConnectCandidate{
+		Plug:                interfaces.NewConnectedPlug(plug, plugAppSet, nil, nil),
+		PlugSnapDeclaration: c.decls[plug.Snap.SnapName()],
+		Slot:                interfaces.NewConnectedSlot(slot, slotAppSet, nil, nil),
+		SlotSnapDeclaration: c.decls[slot.Snap.SnapName()],
+
+		BaseDeclaration: c.baseDecl,
+
+		Model: c.sc.modelAs,
+		Store: c.sc.storeAs,
+	}, nil
+}
+
+// evaluatePair evaluates auto-connection and connection policy for a
+// plug/slot pair.
+func (c *corpus) evaluatePair(plug *snap.PlugInfo, slot *snap.SlotInfo) (*pairVerdict, error) {
+	cc, err := c.connectCandidate(plug, slot)
+	if err != nil {
+		return nil, err
+	}
+	v := &pairVerdict{
+		Interface: plug.Interface,
+		PlugRef:   interfaces.PlugRef{Snap: plug.Snap.SnapName(), Name: plug.Name},
+		SlotRef:   interfaces.SlotRef{Snap: slot.Snap.SnapName(), Name: slot.Name},
+	}
+	arity, autoErr := cc.CheckAutoConnect()
+	if autoErr == nil {
+		v.AllowAutoConnection = true
+		v.SlotsPerPlugAny = arity.SlotsPerPlugAny()
+	} else {
+		v.AutoConnectError = autoErr.Error()
+	}
+	connErr := cc.Check()
+	if connErr == nil {
+		v.AllowConnection = true
+	} else {
+		v.ConnectError = connErr.Error()
+	}
+	switch {
+	case autoErr == nil:
+		v.Verdict = verdictAuto
+	case connErr == nil:
+		v.Verdict = verdictManual
+		v.Reason = v.AutoConnectError
+	default:
+		v.Verdict = verdictDenied
+		v.Reason = v.ConnectError
+	}
+	return v, nil
+}
+
+// autoCandidates returns the slots among the given ones the plug is
+// allowed to auto-connect to and whether all of them allow
+// slots-per-plug: *.
+func (c *corpus) autoCandidates(plug *snap.PlugInfo, slots []*snap.SlotInfo) ([]interfaces.SlotRef, bool, error) {
+	var cands []interfaces.SlotRef
+	slotsPerPlugAny := true
+	for _, slot := range slots {
+		cc, err := c.connectCandidate(plug, slot)
+		if err != nil {
+			return nil, false, err
+		}
+		arity, err := cc.CheckAutoConnect()
+		if err != nil {
+			continue
+		}
+		cands = append(cands, interfaces.SlotRef{Snap: slot.Snap.SnapName(), Name: slot.Name})
+		slotsPerPlugAny = slotsPerPlugAny && arity.SlotsPerPlugAny()
+	}
+	return cands, slotsPerPlugAny, nil
+}
+
+// checkAmbiguity turns an auto verdict into a manual one if the plug is
+// allowed to auto-connect to more than one of the given slots without
+// slots-per-plug: *, as then snapd auto-connects it to none.
+func (c *corpus) checkAmbiguity(v *pairVerdict, plug *snap.PlugInfo, slots []*snap.SlotInfo) error {
+	if v.Verdict != verdictAuto {
+		return nil
+	}
+	cands, slotsPerPlugAny, err := c.autoCandidates(plug, slots)
+	if err != nil {
+		return err
+	}
+	if len(cands) < 2 || slotsPerPlugAny {
+		return nil
+	}
+	sort.Slice(cands, func(i, j int) bool {
+		if cands[i].Snap != cands[j].Snap {
+			return cands[i].Snap < cands[j].Snap
+		}
+		return cands[i].Name < cands[j].Name
+	})
+	v.Verdict = verdictManual
+	v.Reason = fmt.Sprintf("ambiguous auto-connection, %d candidate slots", len(cands))
+	v.Candidates = cands
+	return nil
+}
+
+func sortVerdicts(verdicts []*pairVerdict) {
+	sort.Slice(verdicts, func(i, j int) bool {
+		v1 := verdicts[i]
+		v2 := verdicts[j]
+		if v1.Interface != v2.Interface {
+			return v1.Interface < v2.Interface
+		}
+		return connRefLess(&v1.PlugRef, &v1.SlotRef, &v2.PlugRef, &v2.SlotRef)
+	})
+}
+
+// installations checks the installation of all the snaps in the corpus.
+func (c *corpus) installations() []installation {
+	var insts []installation
+	for _, name := range c.given {
+		if inst := c.refused[name]; inst != nil {
+			insts = append(insts, *inst)
+			continue
+		}
+		mocked := c.sc.installed[name]
+		insts = append(insts, c.sc.checkInstall(name, mocked))
+	}
+	return insts
+}
+
+type reverseLookupSimulation struct {
+	scenarioParams
+
+	// Slot is <snap>:<slot>
+	Slot  string   `json:"slot"`
+	Snaps []string `json:"snaps"`
+}
+
+type reverseLookupResult struct {
+	Interface string             `json:"interface"`
+	SlotRef   interfaces.SlotRef `json:"slot"`
+
+	Installing []installation `json:"installing"`
+
+	Plugs []*pairVerdict `json:"plugs"`
+}
+
+func (s *oneshotSimulation) simulateReverseLookup(params *reverseLookupSimulation) (*reverseLookupResult, error) {
+	snapName, slotName, err := splitSnapRef(params.Slot, "slot")
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.setupCorpus(&params.scenarioParams, params.Snaps)
+	if err != nil {
+		return nil, err
+	}
+	repo := s.mgr.Repository()
+
+	// the slot snap can be given as one of the snap directories or
+	// .snap files, use its name
+	if info := c.sc.infos[snapName]; info != nil {
+		snapName = info.SnapName()
+	}
+	slotRef := interfaces.SlotRef{Snap: snapName, Name: slotName}
+
+	slot := repo.Slot(slotRef.Snap, slotRef.Name)
+	if slot == nil {
+		return nil, fmt.Errorf("cannot find slot %s", params.Slot)
+	}
+	// all the slots a plug could be auto-connected to, for ambiguity
+	ifaceSlots := repo.AllSlots(slot.Interface)
+
+	res := &reverseLookupResult{
+		Interface: slot.Interface,
+		SlotRef:   slotRef,
+	}
+	res.Installing = c.installations()
+	for _, plug := range repo.AllPlugs(slot.Interface) {
+		v, err := c.evaluatePair(plug, slot)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.checkAmbiguity(v, plug, ifaceSlots); err != nil {
+			return nil, err
+		}
+		res.Plugs = append(res.Plugs, v)
+	}
+	sortVerdicts(res.Plugs)
+	return res, nil
+}
+
+type connectionMatrixSimulation struct {
+	scenarioParams
+
+	// Interface restricts the matrix to one interface
+	Interface string `json:"interface"`
+	// IncludeSystem includes the system snap slots
+	IncludeSystem bool     `json:"include-system"`
+	Snaps         []string `json:"snaps"`
+}
+
+type connectionMatrixResult struct {
+	Installing []installation `json:"installing"`
+
+	Pairs []*pairVerdict `json:"pairs"`
+}
+
+// pairs returns all the plug/slot pairs between the snaps of the corpus
+// for the given interface or for all of them, with includeSystem the
+// system snap slots are considered too.
+func (c *corpus) pairs(repo *interfaces.Repository, iface string, includeSystem bool) (plugs []*snap.PlugInfo, slots map[string][]*snap.SlotInfo) {
+	inCorpus := make(map[string]bool, len(c.sc.snaps))
+	for _, name := range c.sc.snaps {
+		inCorpus[c.sc.infos[name].SnapName()] = true
+	}
+	if includeSystem {
+		inCorpus[c.sc.flavour.systemSnap] = true
+	}
+	slots = make(map[string][]*snap.SlotInfo)
+	for _, name := range c.sc.snaps {
+		for _, plug := range repo.Plugs(c.sc.infos[name].SnapName()) {
+			if iface != "" && plug.Interface != iface {
+				continue
+			}
+			plugs = append(plugs, plug)
+			if _, ok := slots[plug.Interface]; ok {
+				continue
+			}
+			ifaceSlots := []*snap.SlotInfo{}
+			for _, slot := range repo.AllSlots(plug.Interface) {
+				if inCorpus[slot.Snap.SnapName()] {
+					ifaceSlots = append(ifaceSlots, slot)
+				}
+			}
+			slots[plug.Interface] = ifaceSlots
+		}
+	}
+	return plugs, slots
+}
+
+func (s *oneshotSimulation) simulateConnectionMatrix(params *connectionMatrixSimulation) (*connectionMatrixResult, error) {
+	c, err := s.setupCorpus(&params.scenarioParams, params.Snaps)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &connectionMatrixResult{
+		Installing: c.installations(),
+	}
+	plugs, slots := c.pairs(s.mgr.Repository(), params.Interface, params.IncludeSystem)
+	for _, plug := range plugs {
+		for _, slot := range slots[plug.Interface] {
+			v, err := c.evaluatePair(plug, slot)
+			if err != nil {
+				return nil, err
+			}
+			res.Pairs = append(res.Pairs, v)
+		}
+	}
+	sortVerdicts(res.Pairs)
+	return res, nil
+}
+
+// splitSnapRef splits a <snap>:<plug-or-slot> reference.
+func splitSnapRef(ref, what string) (snapName, name string, err error) {
+	parts := strings.SplitN(ref, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid %s %q, expected <snap>:<%s>", what, ref, what)
+	}
+	return parts[0], parts[1], nil
+}
+
+// Operations
+
+func reverseLookup(param *json.RawMessage) error {
+	var params reverseLookupSimulation
+	if err := json.Unmarshal([]byte(*param), &params); err != nil {
+		return err
+	}
+
+	sim := oneshotSimulation{}
+	sim.setup(params.Classic)
+	res, err := sim.simulateReverseLookup(&params)
+	if err != nil {
+		return outputError("json", err)
+	}
+	sim.finish()
+
+	b, err := json.Marshal(res)
+	noerror(err)
+	fmt.Println(string(b))
+	return nil
+}
+
+func connectionMatrix(param *json.RawMessage) error {
+	var params connectionMatrixSimulation
+	if err := json.Unmarshal([]byte(*param), &params); err != nil {
+		return err
+	}
+
+	sim := oneshotSimulation{}
+	sim.setup(params.Classic)
+	res, err := sim.simulateConnectionMatrix(&params)
+	if err != nil {
+		return outputError("json", err)
+	}
+	sim.finish()
+
+	b, err := json.Marshal(res)
+	noerror(err)
+	fmt.Println(string(b))
+	return nil
+}
@@ -0,0 +1,78 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/snapcore/snapd/interfaces"
+)
+
+func TestReverseLookupAmbiguity(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeSnapDir(t, "consumer", "pub1", fmt.Sprintf(contentPlugYaml, "consumer"), "", "")
+	writeSnapDir(t, "provider1", "pub1", fmt.Sprintf(contentSlotYaml, "provider1"), "", "")
+	writeSnapDir(t, "provider2", "pub1", fmt.Sprintf(contentSlotYaml, "provider2"), "", "")
+
+	lookup := func(snaps ...string) *pairVerdict {
+		sim := newTestSimulation(t, false)
+		res, err := sim.simulateReverseLookup(&reverseLookupSimulation{
+			Slot:  "provider1:data",
+			Snaps: snaps,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Plugs) != 1 {
+			t.Fatalf("expected one plug, got %+v", res.Plugs)
+		}
+		return res.Plugs[0]
+	}
+
+	// a single slot from the same publisher
+	v := lookup("consumer", "provider1")
+	if v.Verdict != verdictAuto || v.Candidates != nil {
+		t.Errorf("expected an unambiguous auto verdict, got %+v", v)
+	}
+
+	// a second one makes the auto-connection ambiguous
+	v = lookup("consumer", "provider1", "provider2")
+	if v.PlugRef != (interfaces.PlugRef{Snap: "consumer", Name: "data"}) {
+		t.Errorf("unexpected plug %v", v.PlugRef)
+	}
+	if v.Verdict != verdictManual {
+		t.Errorf("expected manual verdict, got %s", v.Verdict)
+	}
+	if !v.AllowAutoConnection {
+		t.Errorf("expected the auto-connection to be allowed by the policy")
+	}
+	if v.Reason != "ambiguous auto-connection, 2 candidate slots" {
+		t.Errorf("unexpected reason %q", v.Reason)
+	}
+	want := []interfaces.SlotRef{
+		{Snap: "provider1", Name: "data"},
+		{Snap: "provider2", Name: "data"},
+	}
+	if !reflect.DeepEqual(v.Candidates, want) {
+		t.Errorf("expected candidates %v, got %v", want, v.Candidates)
+	}
+}
@@ -0,0 +1,337 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/policy"
+)
+
+// baseDeclSource is the source of the base-declaration rules
+const baseDeclSource = "base-declaration"
+
+// ruleKeys are the constraint keys of an interface rule
+var ruleKeys = []string{
+	"allow-installation",
+	"deny-installation",
+	"allow-connection",
+	"deny-connection",
+	"allow-auto-connection",
+	"deny-auto-connection",
+}
+
+type ruleCoverageSimulation struct {
+	scenarioParams
+
+	// IncludeBase includes the base-declaration rules for the
+	// interfaces used by the snaps
+	IncludeBase bool     `json:"include-base"`
+	Snaps       []string `json:"snaps"`
+}
+
+// ruleAlternative is one alternative of the constraints for a key of an
+// interface rule.
+type ruleAlternative struct {
+	// Source is the snap whose declaration has the rule or
+	// base-declaration
+	Source    string `json:"source"`
+	Side      string `json:"side"`
+	Interface string `json:"interface"`
+	Key       string `json:"key"`
+	// Index is the index of the alternative in the list of alternatives
+	Index       int         `json:"index"`
+	Constraints interface{} `json:"constraints"`
+
+	// Matched lists what the alternative matched: snap names for the
+	// installation constraints, <slot snap>:<slot> > <plug snap>:<plug>
+	// otherwise
+	Matched []string `json:"matched"`
+}
+
+type ruleCoverageResult struct {
+	Alternatives []*ruleAlternative `json:"alternatives"`
+}
+
+// coverageItem is something a constraint can be exercised on, allowed
+// reports whether the constraint check relevant for the key passes.
+type coverageItem struct {
+	label   string
+	allowed func() (bool, error)
+}
+
+// installCandidate builds the policy installation candidate for a snap
+// directory of the corpus.
+func (c *corpus) installCandidate(name string) *policy.InstallCandidate {
+	mocked := c.sc.installed[name]
+	return &policy.InstallCandidate{
+		Snap:            mocked.info,
+		SnapDeclaration: c.decls[mocked.info.SnapName()],
+		BaseDeclaration: c.baseDecl,
+		Model:           c.sc.modelAs,
+		Store:           c.sc.storeAs,
+	}
+}
+
+// coverageItems returns the items on which the constraints of a key of
+// an interface rule from the given source get checked.
+func (c *corpus) coverageItems(repo *interfaces.Repository, source, side, iface, key string) []coverageItem {
+	var items []coverageItem
+	if strings.HasSuffix(key, "-installation") {
+		for _, name := range c.sc.snaps {
+			info := c.sc.installed[name].info
+			if source != baseDeclSource && info.SnapName() != source {
+				continue
+			}
+			has := false
+			if side == "plugs" {
+				for _, plug := range info.Plugs {
+					has = has || plug.Interface == iface
+				}
+			} else {
+				for _, slot := range info.Slots {
+					has = has || slot.Interface == iface
+				}
+			}
+			if !has {
+				continue
+			}
+			name := name
+			items = append(items, coverageItem{
+				label: info.SnapName(),
+				allowed: func() (bool, error) {
+					return c.installCandidate(name).Check() == nil, nil
+				},
+			})
+		}
+		return items
+	}
+
+	auto := strings.HasSuffix(key, "-auto-connection")
+	plugs, slots := c.pairs(repo, iface, true)
+	for _, plug := range plugs {
+		for _, slot := range slots[iface] {
+			if source != baseDeclSource {
+				if side == "plugs" && plug.Snap.SnapName() != source {
+					continue
+				}
+				if side == "slots" && slot.Snap.SnapName() != source {
+					continue
+				}
+			}
+			plug, slot := plug, slot
+			items = append(items, coverageItem{
+				label: fmt.Sprintf("%s:%s > %s:%s", slot.Snap.SnapName(), slot.Name, plug.Snap.SnapName(), plug.Name),
+				allowed: func() (bool, error) {
+					cc, err := c.connectCandidate(plug, slot)
+					if err != nil {
+						return false, err
+					}
+					if auto {
+						_, err = cc.CheckAutoConnect()
+					} else {
+						err = cc.Check()
+					}
+					return err == nil, nil
+				},
+			})
+		}
+	}
+	return items
+}
+
+// withConstraints returns a copy of the headers with the constraints
+// for the key of an interface rule replaced.
+func withConstraints(headers map[string]interface{}, side, iface, key string, constraints interface{}) map[string]interface{} {
+	rules := headers[side].(map[string]interface{})
+	rule := make(map[string]interface{})
+	for k, v := range rules[iface].(map[string]interface{}) {
+		rule[k] = v
+	}
+	rule[key] = constraints
+	newRules := make(map[string]interface{}, len(rules))
+	for k, v := range rules {
+		newRules[k] = v
+	}
+	newRules[iface] = rule
+	newHeaders := make(map[string]interface{}, len(headers))
+	for k, v := range headers {
+		newHeaders[k] = v
+	}
+	newHeaders[side] = newRules
+	return newHeaders
+}
+
+// constraintsAlternatives returns the alternatives of a constraints
+// value.
+func constraintsAlternatives(constraints interface{}) []interface{} {
+	if alts, ok := constraints.([]interface{}); ok {
+		return alts
+	}
+	return []interface{}{constraints}
+}
+
+// coverRules computes which alternatives of the rules in the headers
+// of a declaration match some item. An alternative matches an item if
+// the outcome with only that alternative differs from the one with a
+// never matching "false". So an alternative whose constraints hold for
+// an item but whose outcome is masked, e.g. by a deny constraint of the
+// rule matching as well, is reported as not matching it. use is called
+// to use modified headers for the evaluation.
+func (c *corpus) coverRules(repo *interfaces.Repository, source string, headers map[string]interface{}, use func(map[string]interface{}) error) ([]*ruleAlternative, error) {
+	var alts []*ruleAlternative
+	evaluate := func(items []coverageItem, h map[string]interface{}) ([]bool, error) {
+		if err := use(h); err != nil {
+			return nil, err
+		}
+		outcomes := make([]bool, len(items))
+		for i, item := range items {
+			allowed, err := item.allowed()
+			if err != nil {
+				return nil, err
+			}
+			outcomes[i] = allowed
+		}
+		return outcomes, nil
+	}
+	for _, side := range []string{"plugs", "slots"} {
+		rules, ok := headers[side].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, iface := range sortedHeaderKeys(rules) {
+			rule, ok := rules[iface].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, key := range ruleKeys {
+				constraints, ok := rule[key]
+				if !ok {
+					continue
+				}
+				items := c.coverageItems(repo, source, side, iface, key)
+				if len(items) == 0 && source == baseDeclSource {
+					// only the base-declaration rules for the
+					// interfaces in use are of interest
+					continue
+				}
+				never, err := evaluate(items, withConstraints(headers, side, iface, key, "false"))
+				if err != nil {
+					return nil, err
+				}
+				for i, alt := range constraintsAlternatives(constraints) {
+					ruleAlt := &ruleAlternative{
+						Source:      source,
+						Side:        side,
+						Interface:   iface,
+						Key:         key,
+						Index:       i,
+						Constraints: alt,
+					}
+					alts = append(alts, ruleAlt)
+					outcomes, err := evaluate(items, withConstraints(headers, side, iface, key, alt))
+					if err != nil {
+						return nil, fmt.Errorf("cannot evaluate %s %s %s rule: %v", source, side, iface, err)
+					}
+					for j, item := range items {
+						if outcomes[j] != never[j] {
+							ruleAlt.Matched = append(ruleAlt.Matched, item.label)
+						}
+					}
+				}
+			}
+		}
+	}
+	return alts, nil
+}
+
+func (s *oneshotSimulation) simulateRuleCoverage(params *ruleCoverageSimulation) (*ruleCoverageResult, error) {
+	c, err := s.setupCorpus(&params.scenarioParams, params.Snaps)
+	if err != nil {
+		return nil, err
+	}
+	repo := s.mgr.Repository()
+
+	res := &ruleCoverageResult{}
+	for _, name := range c.sc.snaps {
+		snapName := c.sc.infos[name].SnapName()
+		orig := c.decls[snapName]
+		if orig == nil {
+			continue
+		}
+		ref, headers := readDeclHeaders(name)
+		alts, err := c.coverRules(repo, snapName, headers, func(h map[string]interface{}) error {
+			decl, err := s.signSnapDecl(ref.PublisherID, h)
+			if err != nil {
+				return err
+			}
+			c.decls[snapName] = decl
+			return nil
+		})
+		c.decls[snapName] = orig
+		if err != nil {
+			return nil, err
+		}
+		res.Alternatives = append(res.Alternatives, alts...)
+	}
+
+	if params.IncludeBase {
+		orig := c.baseDecl
+		alts, err := c.coverRules(repo, baseDeclSource, orig.Headers(), func(h map[string]interface{}) error {
+			baseDecl, err := baseDeclFromHeaders(h)
+			if err != nil {
+				return err
+			}
+			c.baseDecl = baseDecl
+			return nil
+		})
+		c.baseDecl = orig
+		if err != nil {
+			return nil, err
+		}
+		res.Alternatives = append(res.Alternatives, alts...)
+	}
+	return res, nil
+}
+
+// Operations
+
+func ruleCoverage(param *json.RawMessage) error {
+	var params ruleCoverageSimulation
+	if err := json.Unmarshal([]byte(*param), &params); err != nil {
+		return err
+	}
+
+	sim := oneshotSimulation{}
+	sim.setup(params.Classic)
+	res, err := sim.simulateRuleCoverage(&params)
+	if err != nil {
+		return outputError("json", err)
+	}
+	sim.finish()
+
+	b, err := json.Marshal(res)
+	noerror(err)
+	fmt.Println(string(b))
+	return nil
+}
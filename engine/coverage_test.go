@@ -0,0 +1,88 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestConstraintsAlternatives(t *testing.T) {
+	snapID := map[string]interface{}{"slot-snap-id": []interface{}{"bar-id"}}
+	publisherID := map[string]interface{}{"slot-publisher-id": []interface{}{"bar-pub"}}
+	for _, tc := range []struct {
+		constraints interface{}
+		alts        []interface{}
+	}{
+		{"true", []interface{}{"true"}},
+		{"false", []interface{}{"false"}},
+		{snapID, []interface{}{snapID}},
+		{[]interface{}{snapID, publisherID}, []interface{}{snapID, publisherID}},
+	} {
+		if got := constraintsAlternatives(tc.constraints); !reflect.DeepEqual(got, tc.alts) {
+			t.Errorf("%v: expected %v, got %v", tc.constraints, tc.alts, got)
+		}
+	}
+}
+
+func TestRuleCoverage(t *testing.T) {
+	chdir(t, t.TempDir())
+	// content auto-connects across publishers only with declaration
+	// rules
+	writeSnapDir(t, "provider", "pub2", fmt.Sprintf(contentSlotYaml, "provider"), "", "")
+	writeSnapDir(t, "consumer", "pub1", fmt.Sprintf(contentPlugYaml, "consumer"),
+		`{"content": {"allow-auto-connection": [{"slot-snap-id": ["provider-id"]}, {"slot-snap-id": ["gone-id"]}]}}`, "")
+	// the allow alternative holds but is masked by the deny one
+	writeSnapDir(t, "masked", "pub1", fmt.Sprintf(contentPlugYaml, "masked"),
+		`{"content": {"allow-auto-connection": {"slot-snap-id": ["provider-id"]}, "deny-auto-connection": "true"}}`, "")
+
+	sim := newTestSimulation(t, false)
+	res, err := sim.simulateRuleCoverage(&ruleCoverageSimulation{
+		Snaps: []string{"provider", "consumer", "masked"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type altWant struct {
+		source, key string
+		index       int
+		matched     []string
+	}
+	var got []altWant
+	for _, alt := range res.Alternatives {
+		if alt.Side != "plugs" || alt.Interface != "content" {
+			t.Errorf("unexpected alternative %+v", alt)
+			continue
+		}
+		got = append(got, altWant{alt.Source, alt.Key, alt.Index, alt.Matched})
+	}
+	want := []altWant{
+		{"consumer", "allow-auto-connection", 0, []string{"provider:data > consumer:data"}},
+		// dead
+		{"consumer", "allow-auto-connection", 1, nil},
+		{"masked", "allow-auto-connection", 0, nil},
+		{"masked", "deny-auto-connection", 0, []string{"provider:data > masked:data"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,233 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+type evalAttrsParams struct {
+	// Interface is used to compile the constraints, it matters only
+	// for error messages
+	Interface string `json:"interface"`
+	// Constraints are connection constraints with plug-attributes
+	// and/or slot-attributes, or a list of alternatives of them
+	Constraints interface{}            `json:"constraints"`
+	PlugAttrs   map[string]interface{} `json:"plug-attrs"`
+	SlotAttrs   map[string]interface{} `json:"slot-attrs"`
+}
+
+// attrTrace is the outcome of the constraint for one attribute.
+type attrTrace struct {
+	// Side is plug-attributes or slot-attributes
+	Side       string      `json:"side"`
+	Attribute  string      `json:"attribute"`
+	Constraint interface{} `json:"constraint"`
+	Value      interface{} `json:"value,omitempty"`
+	Match      bool        `json:"match"`
+	Error      string      `json:"error,omitempty"`
+}
+
+type attrsAlternative struct {
+	Index     int          `json:"index"`
+	Match     bool         `json:"match"`
+	PlugError string       `json:"plug-error,omitempty"`
+	SlotError string       `json:"slot-error,omitempty"`
+	Trace     []*attrTrace `json:"trace"`
+}
+
+type evalAttrsResult struct {
+	// Match is set if any alternative matches
+	Match        bool                `json:"match"`
+	Alternatives []*attrsAlternative `json:"alternatives"`
+}
+
+// attrsMap implements asserts.Attrer over plain attributes.
+type attrsMap map[string]interface{}
+
+func (m attrsMap) Lookup(path string) (interface{}, bool) {
+	var v interface{} = map[string]interface{}(m)
+	for _, comp := range strings.Split(path, ".") {
+		sub, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		v, ok = sub[comp]
+		if !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// attrsContext resolves $PLUG() and $SLOT() references.
+type attrsContext struct {
+	plug, slot attrsMap
+}
+
+func (c *attrsContext) PlugAttr(arg string) (interface{}, error) {
+	if v, ok := c.plug.Lookup(arg); ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("plug attribute %q not found", arg)
+}
+
+func (c *attrsContext) SlotAttr(arg string) (interface{}, error) {
+	if v, ok := c.slot.Lookup(arg); ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("slot attribute %q not found", arg)
+}
+
+// normalizeAttrs converts JSON numbers to int64 as snapd does for
+// attributes read from snap.yaml.
+func normalizeAttrs(v interface{}) interface{} {
+	switch x := v.(type) {
+	case float64:
+		if x == math.Trunc(x) {
+			return int64(x)
+		}
+		return x
+	case []interface{}:
+		res := make([]interface{}, len(x))
+		for i, elem := range x {
+			res[i] = normalizeAttrs(elem)
+		}
+		return res
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(x))
+		for k, elem := range x {
+			res[k] = normalizeAttrs(elem)
+		}
+		return res
+	}
+	return v
+}
+
+// compileConnectionConstraints compiles connection constraints as the
+// allow-connection constraints of a plug rule.
+func compileConnectionConstraints(iface string, constraints interface{}) ([]*asserts.PlugConnectionConstraints, error) {
+	rule, err := asserts.CompilePlugRule(iface, map[string]interface{}{
+		"allow-connection": constraints,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rule.AllowConnection, nil
+}
+
+// checkAttrs checks attributes against constraints, nil constraints
+// always match.
+func checkAttrs(ac *asserts.AttributeConstraints, attrs attrsMap, ctx *attrsContext) error {
+	if ac == nil {
+		return nil
+	}
+	return ac.Check(attrs, ctx)
+}
+
+func evalAttrs(params *evalAttrsParams) (*evalAttrsResult, error) {
+	iface := params.Interface
+	if iface == "" {
+		iface = "eval"
+	}
+	ctx := &attrsContext{
+		plug: attrsMap(normalizeAttrs(params.PlugAttrs).(map[string]interface{})),
+		slot: attrsMap(normalizeAttrs(params.SlotAttrs).(map[string]interface{})),
+	}
+
+	compiled, err := compileConnectionConstraints(iface, params.Constraints)
+	if err != nil {
+		return nil, err
+	}
+	alts := constraintsAlternatives(params.Constraints)
+
+	res := &evalAttrsResult{}
+	for i, cstrs := range compiled {
+		alt := &attrsAlternative{Index: i}
+		if err := checkAttrs(cstrs.PlugAttributes, ctx.plug, ctx); err != nil {
+			alt.PlugError = err.Error()
+		}
+		if err := checkAttrs(cstrs.SlotAttributes, ctx.slot, ctx); err != nil {
+			alt.SlotError = err.Error()
+		}
+		alt.Match = alt.PlugError == "" && alt.SlotError == ""
+		res.Match = res.Match || alt.Match
+
+		// trace each attribute constraint on its own
+		raw, _ := alts[i].(map[string]interface{})
+		for _, side := range []string{"plug-attributes", "slot-attributes"} {
+			attrs := ctx.plug
+			if side == "slot-attributes" {
+				attrs = ctx.slot
+			}
+			sideCstrs, _ := raw[side].(map[string]interface{})
+			for _, name := range sortedHeaderKeys(sideCstrs) {
+				trace := &attrTrace{
+					Side:       side,
+					Attribute:  name,
+					Constraint: sideCstrs[name],
+				}
+				trace.Value, _ = attrs.Lookup(name)
+				single, err := compileConnectionConstraints(iface, map[string]interface{}{
+					side: map[string]interface{}{name: sideCstrs[name]},
+				})
+				if err == nil {
+					ac := single[0].PlugAttributes
+					if side == "slot-attributes" {
+						ac = single[0].SlotAttributes
+					}
+					err = checkAttrs(ac, attrs, ctx)
+				}
+				if err != nil {
+					trace.Error = err.Error()
+				} else {
+					trace.Match = true
+				}
+				alt.Trace = append(alt.Trace, trace)
+			}
+		}
+		res.Alternatives = append(res.Alternatives, alt)
+	}
+	return res, nil
+}
+
+// Operations
+
+func evalAttrsOp(param *json.RawMessage) error {
+	var params evalAttrsParams
+	if err := json.Unmarshal([]byte(*param), &params); err != nil {
+		return err
+	}
+
+	res, err := evalAttrs(&params)
+	if err != nil {
+		return outputError("json", err)
+	}
+
+	b, err := json.Marshal(res)
+	noerror(err)
+	fmt.Println(string(b))
+	return nil
+}
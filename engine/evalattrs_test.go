@@ -0,0 +1,194 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeAttrs(t *testing.T) {
+	got := normalizeAttrs(map[string]interface{}{
+		"n": 1.0,
+		"f": 1.5,
+		"s": "x",
+		"l": []interface{}{2.0, "y"},
+		"m": map[string]interface{}{"k": 3.0},
+	})
+	want := map[string]interface{}{
+		"n": int64(1),
+		"f": 1.5,
+		"s": "x",
+		"l": []interface{}{int64(2), "y"},
+		"m": map[string]interface{}{"k": int64(3)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestAttrsMapLookup(t *testing.T) {
+	attrs := attrsMap{
+		"a": map[string]interface{}{
+			"b": map[string]interface{}{"c": "x"},
+		},
+		"s": "y",
+	}
+	for _, tc := range []struct {
+		path  string
+		value interface{}
+		ok    bool
+	}{
+		{"s", "y", true},
+		{"a.b.c", "x", true},
+		{"a.b", map[string]interface{}{"c": "x"}, true},
+		{"a.x", nil, false},
+		{"s.t", nil, false},
+		{"missing", nil, false},
+	} {
+		v, ok := attrs.Lookup(tc.path)
+		if ok != tc.ok || !reflect.DeepEqual(v, tc.value) {
+			t.Errorf("%s: expected %v, %t, got %v, %t", tc.path, tc.value, tc.ok, v, ok)
+		}
+	}
+}
+
+func TestEvalAttrs(t *testing.T) {
+	type traceWant struct {
+		side, attr string
+		value      interface{}
+		match      bool
+	}
+	type altWant struct {
+		match bool
+		trace []traceWant
+	}
+	for _, tc := range []struct {
+		comment     string
+		constraints interface{}
+		plug, slot  map[string]interface{}
+		alts        []altWant
+	}{{
+		comment:     "$SLOT",
+		constraints: map[string]interface{}{"plug-attributes": map[string]interface{}{"content": "$SLOT(content)"}},
+		plug:        map[string]interface{}{"content": "data"},
+		slot:        map[string]interface{}{"content": "data"},
+		alts: []altWant{{true, []traceWant{
+			{"plug-attributes", "content", "data", true},
+		}}},
+	}, {
+		comment:     "$SLOT mismatch",
+		constraints: map[string]interface{}{"plug-attributes": map[string]interface{}{"content": "$SLOT(content)"}},
+		plug:        map[string]interface{}{"content": "data"},
+		slot:        map[string]interface{}{"content": "other"},
+		alts: []altWant{{false, []traceWant{
+			{"plug-attributes", "content", "data", false},
+		}}},
+	}, {
+		comment:     "$PLUG of a nested path",
+		constraints: map[string]interface{}{"slot-attributes": map[string]interface{}{"level": "$PLUG(cfg.level)"}},
+		plug:        map[string]interface{}{"cfg": map[string]interface{}{"level": "high"}},
+		slot:        map[string]interface{}{"level": "high"},
+		alts: []altWant{{true, []traceWant{
+			{"slot-attributes", "level", "high", true},
+		}}},
+	}, {
+		comment: "$MISSING",
+		constraints: map[string]interface{}{
+			"plug-attributes": map[string]interface{}{"extra": "$MISSING"},
+			"slot-attributes": map[string]interface{}{"extra": "$MISSING"},
+		},
+		plug: map[string]interface{}{},
+		slot: map[string]interface{}{"extra": "x"},
+		alts: []altWant{{false, []traceWant{
+			{"plug-attributes", "extra", nil, true},
+			{"slot-attributes", "extra", "x", false},
+		}}},
+	}, {
+		comment:     "regexp",
+		constraints: map[string]interface{}{"slot-attributes": map[string]interface{}{"path": "/dev/tty[0-9]+", "name": "tty.*"}},
+		slot:        map[string]interface{}{"path": "/dev/ttyS0", "name": "ttyS0"},
+		alts: []altWant{{false, []traceWant{
+			{"slot-attributes", "name", "ttyS0", true},
+			// regexps match the whole value
+			{"slot-attributes", "path", "/dev/ttyS0", false},
+		}}},
+	}, {
+		comment:     "nested constraints",
+		constraints: map[string]interface{}{"plug-attributes": map[string]interface{}{"cfg": map[string]interface{}{"level": "high"}}},
+		plug:        map[string]interface{}{"cfg": map[string]interface{}{"level": "high", "other": "x"}},
+		alts: []altWant{{true, []traceWant{
+			{"plug-attributes", "cfg", map[string]interface{}{"level": "high", "other": "x"}, true},
+		}}},
+	}, {
+		comment: "list of alternatives",
+		constraints: []interface{}{
+			map[string]interface{}{"plug-attributes": map[string]interface{}{"mode": "a"}},
+			map[string]interface{}{"plug-attributes": map[string]interface{}{"mode": "b"}},
+		},
+		plug: map[string]interface{}{"mode": "b"},
+		alts: []altWant{{false, []traceWant{
+			{"plug-attributes", "mode", "b", false},
+		}}, {true, []traceWant{
+			{"plug-attributes", "mode", "b", true},
+		}}},
+	}} {
+		res, err := evalAttrs(&evalAttrsParams{
+			Constraints: tc.constraints,
+			PlugAttrs:   tc.plug,
+			SlotAttrs:   tc.slot,
+		})
+		if err != nil {
+			t.Errorf("%s: %v", tc.comment, err)
+			continue
+		}
+		match := false
+		var alts []altWant
+		for _, alt := range res.Alternatives {
+			match = match || alt.Match
+			if alt.Match != (alt.PlugError == "" && alt.SlotError == "") {
+				t.Errorf("%s: inconsistent alternative %+v", tc.comment, alt)
+			}
+			got := altWant{match: alt.Match}
+			for _, trace := range alt.Trace {
+				got.trace = append(got.trace, traceWant{trace.Side, trace.Attribute, trace.Value, trace.Match})
+				if trace.Match != (trace.Error == "") {
+					t.Errorf("%s: inconsistent trace %+v", tc.comment, trace)
+				}
+			}
+			alts = append(alts, got)
+		}
+		if res.Match != match {
+			t.Errorf("%s: expected match %t, got %t", tc.comment, match, res.Match)
+		}
+		if !reflect.DeepEqual(alts, tc.alts) {
+			t.Errorf("%s: expected %+v, got %+v", tc.comment, tc.alts, alts)
+		}
+	}
+}
+
+func TestEvalAttrsInvalidConstraints(t *testing.T) {
+	_, err := evalAttrs(&evalAttrsParams{
+		Constraints: map[string]interface{}{"plug-attributes": "foo"},
+	})
+	if err == nil {
+		t.Errorf("expected invalid constraints error")
+	}
+}
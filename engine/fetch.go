@@ -0,0 +1,619 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/snap/channel"
+	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/store/tooling"
+)
+
+const dashboardURL = "https://dashboard.snapcraft.io/"
+
+// devStore talks to the store dashboard API for snap ids and revision
+// metadata.
+type devStore struct {
+	baseURL string
+	// authorization is the Authorization header value, if any
+	authorization string
+	client        *http.Client
+	// cache is the fetch cache, if any
+	cache *fetchCache
+}
+
+// u1Credentials are the credentials as exported by snapcraft
+// export-login and used in SNAPCRAFT_STORE_CREDENTIALS.
+type u1Credentials struct {
+	T string `json:"t"`
+	V struct {
+		Root      string `json:"r"`
+		Discharge string `json:"d"`
+	} `json:"v"`
+}
+
+// macaroonAuthorization builds the Authorization header value from
+// exported snapcraft credentials, binding the discharge to the root
+// macaroon.
+func macaroonAuthorization(credentials string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(credentials))
+	if err != nil {
+		return "", fmt.Errorf("cannot decode store credentials: %v", err)
+	}
+	var creds u1Credentials
+	if err := json.Unmarshal(b, &creds); err != nil {
+		return "", fmt.Errorf("cannot parse store credentials: %v", err)
+	}
+	if creds.T != "u1-macaroon" {
+		return "", fmt.Errorf("unsupported store credentials type: %q", creds.T)
+	}
+	root, err := store.MacaroonDeserialize(creds.V.Root)
+	if err != nil {
+		return "", err
+	}
+	discharge, err := store.MacaroonDeserialize(creds.V.Discharge)
+	if err != nil {
+		return "", err
+	}
+	discharge.Bind(root.Signature())
+	bound, err := store.MacaroonSerialize(discharge)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Macaroon root=%s, discharge=%s", creds.V.Root, bound), nil
+}
+
+// newDevStore returns a devStore for the store at storeURL, by default
+// the real one which requires credentials, SNAPCRAFT_STORE_CREDENTIALS
+// is used if none are given.
+func newDevStore(storeURL, credentials string) (*devStore, error) {
+	ds := &devStore{
+		baseURL: storeURL,
+		client:  &http.Client{},
+	}
+	if storeURL != "" {
+		// e.g. a mock-store, no authentication
+		if !strings.HasSuffix(ds.baseURL, "/") {
+			ds.baseURL += "/"
+		}
+		return ds, nil
+	}
+	ds.baseURL = dashboardURL
+	if credentials == "" {
+		credentials = os.Getenv("SNAPCRAFT_STORE_CREDENTIALS")
+	}
+	if credentials == "" {
+		return nil, fmt.Errorf("store credentials are needed, set SNAPCRAFT_STORE_CREDENTIALS")
+	}
+	authorization, err := macaroonAuthorization(credentials)
+	if err != nil {
+		return nil, err
+	}
+	ds.authorization = authorization
+	return ds, nil
+}
+
+func (ds *devStore) get(path string, v interface{}) error {
+	req, err := http.NewRequest("GET", ds.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "ifacetool")
+	if ds.authorization != "" {
+		req.Header.Set("Authorization", ds.authorization)
+	}
+	resp, err := ds.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("cannot get %s: %s: %s", path, resp.Status, strings.TrimSpace(string(b)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// snapRef gets the snap-id and publisher-id of a snap.
+func (ds *devStore) snapRef(name string) (*snapRef, error) {
+	var info struct {
+		SnapID    string `json:"snap_id"`
+		Publisher struct {
+			ID string `json:"id"`
+		} `json:"publisher"`
+	}
+	if err := ds.get("dev/api/snaps/info/"+name, &info); err != nil {
+		return nil, err
+	}
+	return &snapRef{
+		SnapName:    name,
+		SnapID:      info.SnapID,
+		PublisherID: info.Publisher.ID,
+	}, nil
+}
+
+// revisionMeta gets the revision number and snap.yaml of a revision of
+// a snap, revision can be latest.
+func (ds *devStore) revisionMeta(name, revision string) (int, string, error) {
+	var rsp struct {
+		Revision struct {
+			Revision int    `json:"revision"`
+			SnapYaml string `json:"snap-yaml"`
+		} `json:"revision"`
+	}
+	if err := ds.get(fmt.Sprintf("api/v2/snaps/%s/revisions/%s?include-yaml=1", name, revision), &rsp); err != nil {
+		return 0, "", err
+	}
+	return rsp.Revision.Revision, rsp.Revision.SnapYaml, nil
+}
+
+// channelRisks are the channel risks from the least to the most risky,
+// a channel without releases follows the next less risky one.
+var channelRisks = []string{"stable", "candidate", "beta", "edge"}
+
+// lessRisky returns the risks less risky than risk, from the closest.
+func lessRisky(risk string) []string {
+	var res []string
+	for _, r := range channelRisks {
+		if r == risk {
+			break
+		}
+		res = append([]string{r}, res...)
+	}
+	return res
+}
+
+// channelRevision resolves the revision of a snap released on a channel
+// for an architecture, using the channel map of the snap.
+func (ds *devStore) channelRevision(name, channelName, architecture string) (int, error) {
+	var rsp struct {
+		ChannelMap []struct {
+			Architecture string `json:"architecture"`
+			Channel      string `json:"channel"`
+			Revision     int    `json:"revision"`
+		} `json:"channel-map"`
+	}
+	if err := ds.get(fmt.Sprintf("api/v2/snaps/%s/channel-map", name), &rsp); err != nil {
+		return 0, err
+	}
+	want, err := channel.Parse(channelName, architecture)
+	if err != nil {
+		return 0, err
+	}
+	released := make(map[string]int)
+	for _, rel := range rsp.ChannelMap {
+		if rel.Architecture != architecture && rel.Architecture != "all" {
+			continue
+		}
+		ch, err := channel.Parse(rel.Channel, architecture)
+		if err != nil {
+			continue
+		}
+		released[ch.Full()] = rel.Revision
+	}
+	if revno, ok := released[want.Full()]; ok {
+		return revno, nil
+	}
+	if want.Branch == "" {
+		// closed channels follow the less risky ones
+		for _, risk := range lessRisky(want.Risk) {
+			ch := channel.Channel{Track: want.Track, Risk: risk}
+			if revno, ok := released[ch.Full()]; ok {
+				return revno, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("%s is not released on %s for %s", name, want.Full(), architecture)
+}
+
+// ensureRef writes the .snap.json for a snap directory, unless it is
+// there already.
+func (ds *devStore) ensureRef(name string) error {
+	if _, err := readRef(name); err == nil {
+		return nil
+	}
+	var entry string
+	if ds.cache != nil {
+		entry = ds.cache.refEntry(name)
+	}
+	if entry == "" || !ds.cache.has(entry) {
+		ref, err := ds.snapRef(name)
+		if err != nil {
+			return err
+		}
+		if entry == "" {
+			if err := os.MkdirAll(name, 0755); err != nil {
+				return err
+			}
+			return writeJSON(name, ".snap.json", ref)
+		}
+		b, err := marshalJSON(name, ".snap.json", ref)
+		if err != nil {
+			return err
+		}
+		if err := ds.cache.put(entry, map[string][]byte{".snap.json": b}); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(name, 0755); err != nil {
+		return err
+	}
+	return ds.cache.link(name, entry, ".snap.json")
+}
+
+// revisionMetaCached is like revisionMeta but consults the cache first
+// for specific revisions, it returns the cache entry if there is a
+// cache.
+func (ds *devStore) revisionMetaCached(name, revision string) (int, string, string, error) {
+	if ds.cache == nil {
+		revno, snapYaml, err := ds.revisionMeta(name, revision)
+		return revno, snapYaml, "", err
+	}
+	ref, err := readRef(name)
+	if err != nil {
+		return 0, "", "", err
+	}
+	if revno, err := strconv.Atoi(revision); err == nil {
+		entry := ds.cache.metaEntry(ref.SnapID, revno)
+		if ds.cache.has(entry) {
+			b, err := ioutil.ReadFile(filepath.Join(entry, "snap.yaml"))
+			if err != nil {
+				return 0, "", "", err
+			}
+			return revno, string(b), entry, nil
+		}
+	}
+	revno, snapYaml, err := ds.revisionMeta(name, revision)
+	if err != nil {
+		return 0, "", "", err
+	}
+	entry := ds.cache.metaEntry(ref.SnapID, revno)
+	if err := ds.cache.put(entry, map[string][]byte{"snap.yaml": []byte(snapYaml)}); err != nil {
+		return 0, "", "", err
+	}
+	return revno, snapYaml, entry, nil
+}
+
+// fetchMeta writes the snap.yaml and revision of a snap directory, when
+// refreshing only if the revision changed. It returns the revision
+// change, if any.
+func (ds *devStore) fetchMeta(name, revision string, refresh bool) (*revisionChange, error) {
+	revno, snapYaml, entry, err := ds.revisionMetaCached(name, revision)
+	if err != nil {
+		return nil, err
+	}
+	change := &revisionChange{
+		Before: readRevision(name, "revision"),
+		After:  revno,
+	}
+	if refresh && change.Before == change.After {
+		return nil, nil
+	}
+	if entry != "" {
+		err = ds.cache.link(name, entry, "snap.yaml")
+	} else {
+		err = writeSnapDirFile(name, "snap.yaml", []byte(snapYaml))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := writeRevision(name, "revision", revno); err != nil {
+		return nil, err
+	}
+	if change.Before == change.After {
+		return nil, nil
+	}
+	return change, nil
+}
+
+type fetchSnap struct {
+	Name string `json:"name"`
+	// Revision defaults to latest
+	Revision int `json:"revision"`
+	// Channel is used instead of Revision to fetch the revision
+	// released there for the architecture
+	Channel string `json:"channel"`
+	// Architecture defaults to the fetch one
+	Architecture string `json:"architecture"`
+	// Local is set if the metadata came from a local file and was
+	// written already
+	Local bool `json:"local"`
+}
+
+// defaultFetchJobs is the default number of snaps fetched concurrently.
+const defaultFetchJobs = 8
+
+type fetchParams struct {
+	Snaps []fetchSnap `json:"snaps"`
+	// Meta and Decls select whether to fetch the metadata and the
+	// snap-declaration rules, the snap ids are always fetched
+	Meta  bool `json:"meta"`
+	Decls bool `json:"decls"`
+	// Refresh only rewrites the snap directories whose revision or
+	// declaration revision changed and reports the changes
+	Refresh bool `json:"refresh"`
+	// Jobs is the number of snaps fetched concurrently
+	Jobs int `json:"jobs"`
+	// Architecture is used to resolve channels, by default amd64
+	Architecture string `json:"architecture"`
+	// Closure fetches as well the snaps needed to simulate the given
+	// ones, see fetchClosure
+	Closure bool `json:"closure"`
+	// Platform are snaps to add to the closure, e.g. providing slots
+	// for the plugs of the given snaps
+	Platform []string `json:"platform"`
+	// ValidationSets are paths of files with validation-set assertions,
+	// the revisions they pin are fetched, without snaps given all the
+	// snaps they mention that are not invalid are
+	ValidationSets []string `json:"validation-sets"`
+
+	// pinned are the revisions pinned by the validation sets
+	pinned map[string]int
+
+	// StoreURL points to an alternative store, e.g. a mock-store
+	StoreURL string `json:"store-url"`
+	// Credentials are exported snapcraft credentials, by default
+	// SNAPCRAFT_STORE_CREDENTIALS
+	Credentials string `json:"credentials"`
+	// Cache is the directory of the fetch cache shared between snap
+	// directories, if any
+	Cache string `json:"cache"`
+}
+
+// fetchChange is what refreshing a snap directory changed.
+type fetchChange struct {
+	Snap         string          `json:"snap"`
+	Revision     *revisionChange `json:"revision,omitempty"`
+	DeclRevision *revisionChange `json:"decl-revision,omitempty"`
+}
+
+type fetchResult struct {
+	Changed   []*fetchChange `json:"changed,omitempty"`
+	Unchanged []string       `json:"unchanged,omitempty"`
+	// Closure are the snaps fetched as dependencies
+	Closure []string `json:"closure,omitempty"`
+	// Scenario is the auto-connections scenario for the closure written
+	// to ScenarioFile
+	Scenario     map[string]interface{} `json:"scenario,omitempty"`
+	ScenarioFile string                 `json:"scenario-file,omitempty"`
+}
+
+// fetchAll fetches the snap directories of the snaps concurrently,
+// using a pool of params.Jobs workers.
+func fetchAll(ds *devStore, tsto *tooling.ToolingStore, snaps []fetchSnap, params *fetchParams) ([]*fetchChange, error) {
+	jobs := params.Jobs
+	if jobs <= 0 {
+		jobs = defaultFetchJobs
+	}
+	changes := make([]*fetchChange, len(snaps))
+	errs := make([]error, len(snaps))
+	todo := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range todo {
+				changes[i], errs[i] = fetchOne(ds, tsto, snaps[i], params)
+			}
+		}()
+	}
+	for i := range snaps {
+		todo <- i
+	}
+	close(todo)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("cannot fetch %s: %v", snaps[i].Name, err)
+		}
+	}
+	return changes, nil
+}
+
+// fetchOne fetches the snap directory of one snap.
+func fetchOne(ds *devStore, tsto *tooling.ToolingStore, fsnap fetchSnap, params *fetchParams) (*fetchChange, error) {
+	if err := ds.ensureRef(fsnap.Name); err != nil {
+		return nil, err
+	}
+	change := &fetchChange{Snap: fsnap.Name}
+	if params.Meta && !fsnap.Local {
+		revision := "latest"
+		if fsnap.Revision != 0 {
+			revision = strconv.Itoa(fsnap.Revision)
+		}
+		var tracking *snapChannel
+		if fsnap.Channel != "" {
+			architecture := fsnap.Architecture
+			if architecture == "" {
+				architecture = params.Architecture
+			}
+			revno, err := ds.channelRevision(fsnap.Name, fsnap.Channel, architecture)
+			if err != nil {
+				return nil, err
+			}
+			revision = strconv.Itoa(revno)
+			ch, err := channel.Parse(fsnap.Channel, architecture)
+			noerror(err)
+			tracking = &snapChannel{
+				Channel:      ch.Full(),
+				Architecture: architecture,
+				Revision:     revno,
+			}
+		}
+		revChange, err := ds.fetchMeta(fsnap.Name, revision, params.Refresh)
+		if err != nil {
+			return nil, err
+		}
+		change.Revision = revChange
+		if err := writeChannel(fsnap.Name, tracking); err != nil {
+			return nil, err
+		}
+	}
+	if params.Decls {
+		declChange, err := fetchDecl(tsto, ds.cache, fsnap.Name, params.Refresh)
+		if err != nil {
+			return nil, err
+		}
+		change.DeclRevision = declChange
+	}
+	return change, nil
+}
+
+// snapChannel records in channel.json the channel a snap directory was
+// fetched from.
+type snapChannel struct {
+	Channel      string `json:"channel"`
+	Architecture string `json:"architecture"`
+	Revision     int    `json:"revision"`
+}
+
+// writeChannel writes or, for snaps not fetched from a channel, removes
+// the channel.json of a snap directory.
+func writeChannel(name string, tracking *snapChannel) error {
+	if tracking == nil {
+		err := os.Remove(filepath.Join(name, "channel.json"))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return writeJSON(name, "channel.json", tracking)
+}
+
+// Operations
+
+func fetch(param *json.RawMessage) error {
+	var params fetchParams
+	if err := json.Unmarshal([]byte(*param), &params); err != nil {
+		return err
+	}
+
+	ds, err := newDevStore(params.StoreURL, params.Credentials)
+	if err != nil {
+		return err
+	}
+	ds.cache, err = newFetchCache(params.Cache)
+	if err != nil {
+		return err
+	}
+
+	var tsto *tooling.ToolingStore
+	if params.Decls {
+		if params.StoreURL != "" {
+			// picked up by the tooling store
+			os.Setenv("UBUNTU_STORE_URL", params.StoreURL)
+		}
+		tsto, err = tooling.NewToolingStore()
+		if err != nil {
+			return err
+		}
+	}
+
+	sets, err := readValidationSets(params.ValidationSets)
+	if err != nil {
+		return err
+	}
+	params.pinned, err = pinnedRevisions(sets)
+	if err != nil {
+		return err
+	}
+	if len(params.Snaps) == 0 {
+		for _, vs := range sets {
+			for _, vsnap := range vs.Snaps() {
+				if vsnap.Presence != asserts.PresenceInvalid {
+					params.Snaps = append(params.Snaps, fetchSnap{Name: vsnap.Name})
+				}
+			}
+		}
+	}
+
+	// each snap directory is written by only one worker
+	var snaps []fetchSnap
+	seen := make(map[string]bool)
+	for _, fsnap := range params.Snaps {
+		if strings.ContainsAny(fsnap.Name, "./") {
+			return fmt.Errorf("invalid snap name: %s", fsnap.Name)
+		}
+		if seen[fsnap.Name] {
+			continue
+		}
+		seen[fsnap.Name] = true
+		if rev, ok := params.pinned[fsnap.Name]; ok && !fsnap.Local {
+			if (fsnap.Revision != 0 && fsnap.Revision != rev) || fsnap.Channel != "" {
+				return fmt.Errorf("cannot fetch %s other than at revision %d pinned by the validation sets", fsnap.Name, rev)
+			}
+			fsnap.Revision = rev
+		}
+		snaps = append(snaps, fsnap)
+	}
+
+	if params.Architecture == "" {
+		params.Architecture = "amd64"
+	}
+
+	changes, err := fetchAll(ds, tsto, snaps, &params)
+	if err != nil {
+		return err
+	}
+
+	var res fetchResult
+	if params.Closure {
+		if len(snaps) == 0 {
+			return fmt.Errorf("no snaps to fetch the closure of")
+		}
+		closureChanges, err := fetchClosure(ds, tsto, snaps, seen, &params, &res)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, closureChanges...)
+	} else if !params.Refresh {
+		return nil
+	}
+
+	if params.Refresh {
+		res.Changed = []*fetchChange{}
+		res.Unchanged = []string{}
+		for _, change := range changes {
+			if change.Revision == nil && change.DeclRevision == nil {
+				res.Unchanged = append(res.Unchanged, change.Snap)
+				continue
+			}
+			res.Changed = append(res.Changed, change)
+		}
+	}
+	b, err := json.Marshal(res)
+	noerror(err)
+	fmt.Println(string(b))
+	return nil
+}
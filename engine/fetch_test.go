@@ -0,0 +1,342 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDevStore serves the revisions and channel maps of snaps as the
+// store dashboard API, by snap name.
+type fakeDevStore struct {
+	mu          sync.Mutex
+	revisions   map[string]int
+	channelMaps map[string][]fakeRelease
+	requests    int
+}
+
+type fakeRelease struct {
+	Architecture string `json:"architecture"`
+	Channel      string `json:"channel"`
+	Revision     int    `json:"revision"`
+}
+
+func (fs *fakeDevStore) setRevision(name string, revno int) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.revisions[name] = revno
+}
+
+func (fs *fakeDevStore) requestCount() int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.requests
+}
+
+func (fs *fakeDevStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.requests++
+	// /api/v2/snaps/<name>/revisions/<revision> or
+	// /api/v2/snaps/<name>/channel-map
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v2/snaps/"), "/")
+	if len(parts) == 2 && parts[1] == "channel-map" {
+		rels, ok := fs.channelMaps[parts[0]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"channel-map": rels,
+		})
+		return
+	}
+	if len(parts) != 3 || parts[1] != "revisions" {
+		http.NotFound(w, r)
+		return
+	}
+	name, revision := parts[0], parts[2]
+	revno, ok := fs.revisions[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	var rsp struct {
+		Revision struct {
+			Revision int    `json:"revision"`
+			SnapYaml string `json:"snap-yaml"`
+		} `json:"revision"`
+	}
+	rsp.Revision.Revision = revno
+	rsp.Revision.SnapYaml = "name: " + name + "\nversion: " + revision + "\n"
+	json.NewEncoder(w).Encode(&rsp)
+}
+
+func newTestDevStore(t *testing.T, fs *fakeDevStore) *devStore {
+	srv := httptest.NewServer(fs)
+	t.Cleanup(srv.Close)
+	ds, err := newDevStore(srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ds
+}
+
+func TestReadRevision(t *testing.T) {
+	chdir(t, t.TempDir())
+	if err := os.Mkdir("foo", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if rev := readRevision("foo", "revision"); rev != -1 {
+		t.Errorf("expected -1 for a missing revision, got %d", rev)
+	}
+	for content, want := range map[string]int{
+		"12\n":    12,
+		"0\n":     0,
+		"local\n": -1,
+		"":        -1,
+	} {
+		if err := ioutil.WriteFile(filepath.Join("foo", "revision"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if rev := readRevision("foo", "revision"); rev != want {
+			t.Errorf("expected %d for %q, got %d", want, content, rev)
+		}
+	}
+
+	if err := writeRevision("foo", "decl-revision", 0); err != nil {
+		t.Fatal(err)
+	}
+	if rev := readRevision("foo", "decl-revision"); rev != 0 {
+		t.Errorf("expected decl-revision 0, got %d", rev)
+	}
+}
+
+func TestFetchMetaRefresh(t *testing.T) {
+	chdir(t, t.TempDir())
+	if err := os.Mkdir("foo", 0755); err != nil {
+		t.Fatal(err)
+	}
+	fs := &fakeDevStore{revisions: map[string]int{"foo": 1}}
+	ds := newTestDevStore(t, fs)
+
+	change, err := ds.fetchMeta("foo", "latest", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change == nil || *change != (revisionChange{Before: -1, After: 1}) {
+		t.Errorf("unexpected change %+v", change)
+	}
+	if rev := readRevision("foo", "revision"); rev != 1 {
+		t.Errorf("expected revision 1, got %d", rev)
+	}
+
+	// a local modification is kept if the revision did not change
+	if err := ioutil.WriteFile(filepath.Join("foo", "snap.yaml"), []byte("name: foo\n# local\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	change, err = ds.fetchMeta("foo", "latest", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change != nil {
+		t.Errorf("unexpected change %+v", change)
+	}
+	if got := readFile(t, filepath.Join("foo", "snap.yaml")); got != "name: foo\n# local\n" {
+		t.Errorf("snap.yaml was rewritten: %q", got)
+	}
+
+	fs.setRevision("foo", 2)
+	change, err = ds.fetchMeta("foo", "latest", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change == nil || *change != (revisionChange{Before: 1, After: 2}) {
+		t.Errorf("unexpected change %+v", change)
+	}
+	if rev := readRevision("foo", "revision"); rev != 2 {
+		t.Errorf("expected revision 2, got %d", rev)
+	}
+	if got := readFile(t, filepath.Join("foo", "snap.yaml")); got != "name: foo\nversion: latest\n" {
+		t.Errorf("unexpected snap.yaml %q", got)
+	}
+}
+
+func TestFetchMetaCached(t *testing.T) {
+	chdir(t, t.TempDir())
+	if err := os.Mkdir("foo", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeJSON("foo", ".snap.json", &snapRef{SnapName: "foo", SnapID: "foo-id", PublisherID: "pub"}); err != nil {
+		t.Fatal(err)
+	}
+	fs := &fakeDevStore{revisions: map[string]int{"foo": 7}}
+	ds := newTestDevStore(t, fs)
+	cache, err := newFetchCache("cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds.cache = cache
+
+	if _, err := ds.fetchMeta("foo", "7", false); err != nil {
+		t.Fatal(err)
+	}
+	if fs.requestCount() != 1 {
+		t.Fatalf("expected 1 request, got %d", fs.requestCount())
+	}
+	target, err := os.Readlink(filepath.Join("foo", "snap.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(mustAbs(t, cache.metaEntry("foo-id", 7)), "snap.yaml"); target != want {
+		t.Errorf("expected link to %s, got %s", want, target)
+	}
+
+	// a specific revision in the cache needs no request
+	if err := os.Remove(filepath.Join("foo", "snap.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.fetchMeta("foo", "7", false); err != nil {
+		t.Fatal(err)
+	}
+	if fs.requestCount() != 1 {
+		t.Errorf("expected no more requests, got %d", fs.requestCount())
+	}
+	if got := readFile(t, filepath.Join("foo", "snap.yaml")); got != "name: foo\nversion: 7\n" {
+		t.Errorf("unexpected snap.yaml %q", got)
+	}
+}
+
+func TestWriteDeclRulesDropsSides(t *testing.T) {
+	chdir(t, t.TempDir())
+	cache, err := newFetchCache("cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name  string
+		cache *fetchCache
+	}{
+		{"nocache", nil},
+		{"cached", cache},
+	} {
+		if err := os.Mkdir(tc.name, 0755); err != nil {
+			t.Fatal(err)
+		}
+		both := map[string][]byte{
+			"plugs.json": []byte("{}\n"),
+			"slots.json": []byte("{}\n"),
+		}
+		if err := writeDeclRules(tc.cache, tc.name, tc.name+"-id", 1, both); err != nil {
+			t.Fatal(err)
+		}
+		for what := range both {
+			if _, err := os.Stat(filepath.Join(tc.name, what)); err != nil {
+				t.Errorf("%s: expected %s: %v", tc.name, what, err)
+			}
+		}
+
+		// revision 2 drops the slots rules
+		plugsOnly := map[string][]byte{
+			"plugs.json": []byte(`{"x11": {}}` + "\n"),
+		}
+		if err := writeDeclRules(tc.cache, tc.name, tc.name+"-id", 2, plugsOnly); err != nil {
+			t.Fatal(err)
+		}
+		if got := readFile(t, filepath.Join(tc.name, "plugs.json")); got != `{"x11": {}}`+"\n" {
+			t.Errorf("%s: unexpected plugs.json %q", tc.name, got)
+		}
+		if _, err := os.Lstat(filepath.Join(tc.name, "slots.json")); !os.IsNotExist(err) {
+			t.Errorf("%s: expected slots.json to be removed, got %v", tc.name, err)
+		}
+		if rev := readRevision(tc.name, "decl-revision"); rev != 2 {
+			t.Errorf("%s: expected decl-revision 2, got %d", tc.name, rev)
+		}
+	}
+}
+
+func TestLessRisky(t *testing.T) {
+	for risk, want := range map[string][]string{
+		"stable":    nil,
+		"candidate": {"stable"},
+		"beta":      {"candidate", "stable"},
+		"edge":      {"beta", "candidate", "stable"},
+	} {
+		if got := lessRisky(risk); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %v, got %v", risk, want, got)
+		}
+	}
+}
+
+func TestChannelRevision(t *testing.T) {
+	fs := &fakeDevStore{channelMaps: map[string][]fakeRelease{
+		"foo": {
+			{"amd64", "latest/stable", 1},
+			{"amd64", "latest/candidate", 2},
+			{"arm64", "latest/edge", 3},
+			{"all", "latest/beta", 4},
+			{"amd64", "2.0/stable", 5},
+		},
+	}}
+	ds := newTestDevStore(t, fs)
+
+	for _, tc := range []struct {
+		channel, arch string
+		revno         int
+		err           string
+	}{
+		{"latest/stable", "amd64", 1, ""},
+		{"latest/candidate", "amd64", 2, ""},
+		{"2.0/stable", "amd64", 5, ""},
+		{"latest/edge", "arm64", 3, ""},
+		// architecture all releases apply to all
+		{"latest/beta", "arm64", 4, ""},
+		// closed channels follow the less risky ones
+		{"latest/edge", "amd64", 4, ""},
+		{"2.0/edge", "amd64", 5, ""},
+		{"latest/stable", "arm64", 0, "foo is not released on latest/stable for arm64"},
+		// branches do not follow
+		{"latest/stable/fix", "amd64", 0, "foo is not released on latest/stable/fix for amd64"},
+	} {
+		revno, err := ds.channelRevision("foo", tc.channel, tc.arch)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("%s %s: expected error %q, got %v", tc.channel, tc.arch, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %s: unexpected error %v", tc.channel, tc.arch, err)
+			continue
+		}
+		if revno != tc.revno {
+			t.Errorf("%s %s: expected revision %d, got %d", tc.channel, tc.arch, tc.revno, revno)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+)
+
+type edgeKind int
+
+const (
+	edgeAuto edgeKind = iota
+	edgeManual
+	edgeDenied
+)
+
+var edgeColors = map[edgeKind]string{
+	edgeAuto:   "#2e7d32",
+	edgeManual: "#ef6c00",
+	edgeDenied: "#c62828",
+}
+
+type diagramNode struct {
+	id       string
+	label    string
+	slot     bool
+	dangling bool
+}
+
+type diagramEdge struct {
+	// edges go from slots to plugs
+	from, to string
+	kind     edgeKind
+	label    string
+}
+
+// diagram is a connection graph with snaps as clusters and
+// plugs and slots as nodes.
+type diagram struct {
+	snaps []string
+	nodes map[string][]*diagramNode
+	byKey map[string]*diagramNode
+	edges []diagramEdge
+}
+
+func newDiagram() *diagram {
+	return &diagram{
+		nodes: make(map[string][]*diagramNode),
+		byKey: make(map[string]*diagramNode),
+	}
+}
+
+func (d *diagram) node(snapName, name, iface string, slot bool) *diagramNode {
+	key := fmt.Sprintf("%s:%s:%t", snapName, name, slot)
+	if n := d.byKey[key]; n != nil {
+		return n
+	}
+	if _, ok := d.nodes[snapName]; !ok {
+		d.snaps = append(d.snaps, snapName)
+	}
+	n := &diagramNode{
+		id:    fmt.Sprintf("n%d", len(d.byKey)),
+		label: fmt.Sprintf("%s (%s)", name, iface),
+		slot:  slot,
+	}
+	d.byKey[key] = n
+	d.nodes[snapName] = append(d.nodes[snapName], n)
+	return n
+}
+
+func (d *diagram) plug(ref *interfaces.PlugRef, iface string) *diagramNode {
+	return d.node(ref.Snap, ref.Name, iface, false)
+}
+
+func (d *diagram) slot(ref *interfaces.SlotRef, iface string) *diagramNode {
+	return d.node(ref.Snap, ref.Name, iface, true)
+}
+
+func (d *diagram) edge(slot, plug *diagramNode, kind edgeKind, label string) {
+	d.edges = append(d.edges, diagramEdge{
+		from:  slot.id,
+		to:    plug.id,
+		kind:  kind,
+		label: label,
+	})
+}
+
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+func (d *diagram) writeDot(w io.Writer) {
+	fmt.Fprintf(w, "digraph connections {\n")
+	fmt.Fprintf(w, "\trankdir=LR;\n")
+	for i, snapName := range d.snaps {
+		fmt.Fprintf(w, "\tsubgraph cluster_%d {\n", i)
+		fmt.Fprintf(w, "\t\tlabel=%s;\n", dotQuote(snapName))
+		for _, n := range d.nodes[snapName] {
+			shape := "ellipse"
+			if n.slot {
+				shape = "box"
+			}
+			style := "solid"
+			if n.dangling {
+				style = "dashed"
+			}
+			fmt.Fprintf(w, "\t\t%s [label=%s, shape=%s, style=%s];\n", n.id, dotQuote(n.label), shape, style)
+		}
+		fmt.Fprintf(w, "\t}\n")
+	}
+	for _, e := range d.edges {
+		style := "solid"
+		if e.kind != edgeAuto {
+			style = "dashed"
+		}
+		attrs := fmt.Sprintf("color=%s, style=%s", dotQuote(edgeColors[e.kind]), style)
+		if e.label != "" {
+			attrs += fmt.Sprintf(", label=%s", dotQuote(e.label))
+		}
+		fmt.Fprintf(w, "\t%s -> %s [%s];\n", e.from, e.to, attrs)
+	}
+	fmt.Fprintf(w, "}\n")
+}
+
+func mermaidQuote(s string) string {
+	r := strings.NewReplacer(`"`, "#quot;", "\n", "<br>")
+	return `"` + r.Replace(s) + `"`
+}
+
+func (d *diagram) writeMermaid(w io.Writer) {
+	fmt.Fprintf(w, "flowchart LR\n")
+	var dangling []string
+	for i, snapName := range d.snaps {
+		fmt.Fprintf(w, "  subgraph s%d[%s]\n", i, mermaidQuote(snapName))
+		for _, n := range d.nodes[snapName] {
+			if n.slot {
+				fmt.Fprintf(w, "    %s[%s]\n", n.id, mermaidQuote(n.label))
+			} else {
+				fmt.Fprintf(w, "    %s([%s])\n", n.id, mermaidQuote(n.label))
+			}
+			if n.dangling {
+				dangling = append(dangling, n.id)
+			}
+		}
+		fmt.Fprintf(w, "  end\n")
+	}
+	for _, e := range d.edges {
+		arrow := "-->"
+		if e.kind != edgeAuto {
+			arrow = "-.->"
+		}
+		if e.label != "" {
+			fmt.Fprintf(w, "  %s %s|%s| %s\n", e.from, arrow, mermaidQuote(e.label), e.to)
+		} else {
+			fmt.Fprintf(w, "  %s %s %s\n", e.from, arrow, e.to)
+		}
+	}
+	for i, e := range d.edges {
+		fmt.Fprintf(w, "  linkStyle %d stroke:%s\n", i, edgeColors[e.kind])
+	}
+	if len(dangling) != 0 {
+		fmt.Fprintf(w, "  classDef dangling stroke-dasharray: 5 5\n")
+		fmt.Fprintf(w, "  class %s dangling\n", strings.Join(dangling, ","))
+	}
+}
+
+// diagram builds the diagram of an auto-connections simulation result:
+// the established connections, the candidates that were not
+// auto-connected and the plugs of the target left unconnected.
+func (r *autoConnectSimulationResult) diagram() *diagram {
+	d := newDiagram()
+	connectedPlugs := make(map[interfaces.PlugRef]bool, len(r.Connections))
+	for _, conn := range r.Connections {
+		connectedPlugs[conn.PlugRef] = true
+	}
+	for _, plug := range r.Plugs {
+		plugRef := interfaces.PlugRef{Snap: r.targetSnap, Name: plug.Name}
+		d.plug(&plugRef, plug.Interface).dangling = !connectedPlugs[plugRef]
+	}
+	for _, slot := range r.Slots {
+		d.slot(&interfaces.SlotRef{Snap: r.targetSnap, Name: slot.Name}, slot.Interface)
+	}
+	connected := make(map[interfaces.ConnRef]bool, len(r.Connections))
+	for _, conn := range r.Connections {
+		connected[interfaces.ConnRef{PlugRef: conn.PlugRef, SlotRef: conn.SlotRef}] = true
+		d.edge(d.slot(&conn.SlotRef, conn.Interface), d.plug(&conn.PlugRef, conn.Interface), edgeAuto, "")
+	}
+	for _, cands := range []map[string][]candidate{r.SlotCandidates, r.PlugCandidates} {
+		for _, name := range sortedKeys(cands) {
+			for _, cand := range cands[name] {
+				connRef := interfaces.ConnRef{PlugRef: cand.PlugRef, SlotRef: cand.SlotRef}
+				if connected[connRef] {
+					continue
+				}
+				// avoid duplicates
+				connected[connRef] = true
+				kind := edgeManual
+				label := cand.CheckError
+				switch {
+				case cand.ConnectError != "":
+					kind = edgeDenied
+					label = cand.ConnectError
+				case cand.CheckError == "":
+					label = "ambiguous"
+				}
+				d.edge(d.slot(&cand.SlotRef, cand.Interface), d.plug(&cand.PlugRef, cand.Interface), kind, label)
+			}
+		}
+	}
+	return d
+}
+
+// diagram builds the diagram of a system-graph simulation result, of
+// the system snaps only the slots that got connected are included.
+// system-graph does not collect candidates, so unlike for
+// auto-connections there are no edges for rejected candidates, only the
+// established connections and the dangling plugs.
+func (r *systemGraphResult) diagram() *diagram {
+	d := newDiagram()
+	connectedPlugs := make(map[interfaces.PlugRef]bool, len(r.Connections))
+	for _, conn := range r.Connections {
+		connectedPlugs[conn.PlugRef] = true
+	}
+	for _, gsnap := range r.Snaps {
+		if gsnap.Name == r.SystemSnap {
+			continue
+		}
+		for _, slot := range gsnap.Slots {
+			d.slot(&interfaces.SlotRef{Snap: gsnap.Name, Name: slot.Name}, slot.Interface)
+		}
+		for _, plug := range gsnap.Plugs {
+			plugRef := interfaces.PlugRef{Snap: gsnap.Name, Name: plug.Name}
+			d.plug(&plugRef, plug.Interface).dangling = !connectedPlugs[plugRef]
+		}
+	}
+	for _, conn := range r.Connections {
+		kind := edgeAuto
+		if !conn.Auto {
+			kind = edgeManual
+		}
+		label := ""
+		if conn.ByGadget {
+			label = "gadget"
+		}
+		d.edge(d.slot(&conn.SlotRef, conn.Interface), d.plug(&conn.PlugRef, conn.Interface), kind, label)
+	}
+	return d
+}
+
+func sortedKeys(m map[string][]candidate) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type diagrammable interface {
+	diagram() *diagram
+}
+
+func checkOutputFormat(format string) error {
+	switch format {
+	case "", "json", "dot", "mermaid":
+		return nil
+	}
+	return fmt.Errorf("unsupported output format: %s", format)
+}
+
+// outputResult writes the simulation result to stdout in the given format.
+func outputResult(format string, res diagrammable) error {
+	w := bufio.NewWriter(os.Stdout)
+	switch format {
+	case "dot":
+		res.diagram().writeDot(w)
+	case "mermaid":
+		res.diagram().writeMermaid(w)
+	default:
+		b, err := json.Marshal(res)
+		noerror(err)
+		fmt.Fprintln(w, string(b))
+	}
+	return w.Flush()
+}
+
+// outputError reports simulation errors as JSON for the JSON output,
+// otherwise as an engine error.
+func outputError(format string, err error) error {
+	if format != "" && format != "json" {
+		return err
+	}
+	var errRes struct {
+		Error string `json:"error"`
+	}
+	errRes.Error = err.Error()
+	b, err := json.Marshal(&errRes)
+	noerror(err)
+	fmt.Println(string(b))
+	return nil
+}
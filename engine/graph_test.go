@@ -0,0 +1,101 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/snapcore/snapd/interfaces"
+)
+
+// testDiagram has all the edge kinds, a dangling plug and labels to
+// quote.
+func testDiagram() *diagram {
+	d := newDiagram()
+	x11Plug := d.plug(&interfaces.PlugRef{Snap: "foo", Name: "x11"}, "x11")
+	x11Slot := d.slot(&interfaces.SlotRef{Snap: "snapd", Name: "x11"}, "x11")
+	homePlug := d.plug(&interfaces.PlugRef{Snap: "foo", Name: "home"}, "home")
+	homePlug.dangling = true
+	dataPlug := d.plug(&interfaces.PlugRef{Snap: "foo", Name: "data"}, "content")
+	dataSlot := d.slot(&interfaces.SlotRef{Snap: `my"snap`, Name: "data"}, "content")
+	d.edge(x11Slot, x11Plug, edgeAuto, "")
+	d.edge(dataSlot, dataPlug, edgeManual, "not allowed:\nplug \"x\"")
+	d.edge(x11Slot, homePlug, edgeDenied, "denied")
+	return d
+}
+
+func TestWriteDot(t *testing.T) {
+	var buf bytes.Buffer
+	testDiagram().writeDot(&buf)
+	want := `digraph connections {
+	rankdir=LR;
+	subgraph cluster_0 {
+		label="foo";
+		n0 [label="x11 (x11)", shape=ellipse, style=solid];
+		n2 [label="home (home)", shape=ellipse, style=dashed];
+		n3 [label="data (content)", shape=ellipse, style=solid];
+	}
+	subgraph cluster_1 {
+		label="snapd";
+		n1 [label="x11 (x11)", shape=box, style=solid];
+	}
+	subgraph cluster_2 {
+		label="my\"snap";
+		n4 [label="data (content)", shape=box, style=solid];
+	}
+	n1 -> n0 [color="#2e7d32", style=solid];
+	n4 -> n3 [color="#ef6c00", style=dashed, label="not allowed:\nplug \"x\""];
+	n1 -> n2 [color="#c62828", style=dashed, label="denied"];
+}
+`
+	if buf.String() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	var buf bytes.Buffer
+	testDiagram().writeMermaid(&buf)
+	want := `flowchart LR
+  subgraph s0["foo"]
+    n0(["x11 (x11)"])
+    n2(["home (home)"])
+    n3(["data (content)"])
+  end
+  subgraph s1["snapd"]
+    n1["x11 (x11)"]
+  end
+  subgraph s2["my#quot;snap"]
+    n4["data (content)"]
+  end
+  n1 --> n0
+  n4 -.->|"not allowed:<br>plug #quot;x#quot;"| n3
+  n1 -.->|"denied"| n2
+  linkStyle 0 stroke:#2e7d32
+  linkStyle 1 stroke:#ef6c00
+  linkStyle 2 stroke:#c62828
+  classDef dangling stroke-dasharray: 5 5
+  class n2 dangling
+`
+	if buf.String() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
@@ -33,10 +33,44 @@ func run() error {
 	param := json.RawMessage(os.Args[2])
 
 	switch op {
+	case "fetch":
+		return fetch(&param)
 	case "fetch-decls":
 		return fetchDecls(&param)
+	case "read-snap":
+		return readSnapOp(&param)
 	case "auto-connections":
 		return autoConnections(&param)
+	case "system-graph":
+		return systemGraph(&param)
+	case "reverse-lookup":
+		return reverseLookup(&param)
+	case "connection-matrix":
+		return connectionMatrix(&param)
+	case "suggest":
+		return suggest(&param)
+	case "over-grant":
+		return overGrant(&param)
+	case "rule-coverage":
+		return ruleCoverage(&param)
+	case "base-declaration":
+		return baseDeclaration(&param)
+	case "interfaces":
+		return interfacesOp(&param)
+	case "check-meta":
+		return checkMetaOp(&param)
+	case "eval-attrs":
+		return evalAttrsOp(&param)
+	case "mock-store":
+		return mockStoreOp(&param)
+	case "export":
+		return exportOp(&param)
+	case "import":
+		return importOp(&param)
+	case "replay":
+		return replayOp(&param)
+	case "version":
+		return versionOp(&param)
 	default:
 		return fmt.Errorf("invalid engine op: %s", op)
 	}
@@ -0,0 +1,265 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// mockStore serves snap info, revision metadata, channel maps and
+// snap-declarations from a directory of snap directories, laid out as
+// written by fetch. The snap-declarations are signed with mock store
+// keys.
+type mockStore struct {
+	dir string
+
+	mu sync.Mutex
+	am *assertsMock
+}
+
+func newMockStore(dir string) *mockStore {
+	am := &assertsMock{}
+	am.setupAsserts(state.New(nil))
+	return &mockStore{
+		dir: dir,
+		am:  am,
+	}
+}
+
+func (ms *mockStore) snapDir(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, "./") {
+		return "", fmt.Errorf("invalid snap name: %q", name)
+	}
+	snapDir := filepath.Join(ms.dir, name)
+	if _, err := readRef(snapDir); err != nil {
+		return "", err
+	}
+	return snapDir, nil
+}
+
+// snapDirByID finds the directory of the snap with the given snap-id.
+func (ms *mockStore) snapDirByID(snapID string) (string, error) {
+	entries, err := ioutil.ReadDir(ms.dir)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		snapDir := filepath.Join(ms.dir, entry.Name())
+		ref, err := readRef(snapDir)
+		if err != nil {
+			continue
+		}
+		if ref.SnapID == snapID {
+			return snapDir, nil
+		}
+	}
+	return "", os.ErrNotExist
+}
+
+func writeStoreError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	b, _ := json.Marshal(map[string]interface{}{
+		"error-list": []map[string]string{{
+			"code":    http.StatusText(status),
+			"message": err.Error(),
+		}},
+	})
+	w.Write(b)
+}
+
+func writeStoreJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	b, err := json.Marshal(v)
+	noerror(err)
+	w.Write(b)
+}
+
+// snapInfo serves /dev/api/snaps/info/<name>.
+func (ms *mockStore) snapInfo(w http.ResponseWriter, r *http.Request) {
+	snapDir, err := ms.snapDir(strings.TrimPrefix(r.URL.Path, "/dev/api/snaps/info/"))
+	if err != nil {
+		writeStoreError(w, http.StatusNotFound, err)
+		return
+	}
+	ref, _ := readRef(snapDir)
+	writeStoreJSON(w, map[string]interface{}{
+		"snap_id":   ref.SnapID,
+		"snap_name": ref.SnapName,
+		"publisher": map[string]string{
+			"id": ref.PublisherID,
+		},
+	})
+}
+
+// snapRevisions dispatches /api/v2/snaps/<name>/revisions/<revision>
+// and /api/v2/snaps/<name>/channel-map.
+func (ms *mockStore) snapRevisions(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v2/snaps/"), "/")
+	switch {
+	case len(parts) == 3 && parts[1] == "revisions":
+		ms.snapRevision(w, parts)
+	case len(parts) == 2 && parts[1] == "channel-map":
+		ms.channelMap(w, parts)
+	default:
+		writeStoreError(w, http.StatusNotFound, fmt.Errorf("not found: %s", r.URL.Path))
+	}
+}
+
+// snapDirRevision returns the revision of a snap directory, local snaps
+// have their path as revision and are served as revision 1.
+func snapDirRevision(snapDir string) int {
+	revision := readRevision(snapDir, "revision")
+	if revision < 1 {
+		revision = 1
+	}
+	return revision
+}
+
+// snapRevision serves the revision metadata, only the revision in the
+// snap directory or latest are available.
+func (ms *mockStore) snapRevision(w http.ResponseWriter, parts []string) {
+	snapDir, err := ms.snapDir(parts[0])
+	if err != nil {
+		writeStoreError(w, http.StatusNotFound, err)
+		return
+	}
+	snapYaml, err := ioutil.ReadFile(filepath.Join(snapDir, "snap.yaml"))
+	if err != nil {
+		writeStoreError(w, http.StatusNotFound, err)
+		return
+	}
+	revision := snapDirRevision(snapDir)
+	if parts[2] != "latest" && parts[2] != strconv.Itoa(revision) {
+		writeStoreError(w, http.StatusNotFound, fmt.Errorf("no revision %s of %s", parts[2], parts[0]))
+		return
+	}
+	writeStoreJSON(w, map[string]interface{}{
+		"revision": map[string]interface{}{
+			"revision":  revision,
+			"snap-yaml": string(snapYaml),
+		},
+	})
+}
+
+// channelMap serves the channel map, the revision in the snap directory
+// is released on the channel in its channel.json, by default
+// latest/stable, for the architectures in its snap.yaml.
+func (ms *mockStore) channelMap(w http.ResponseWriter, parts []string) {
+	snapDir, err := ms.snapDir(parts[0])
+	if err != nil {
+		writeStoreError(w, http.StatusNotFound, err)
+		return
+	}
+	info, err := readSnapInfo(snapDir)
+	if err != nil {
+		writeStoreError(w, http.StatusNotFound, err)
+		return
+	}
+	channelName := "latest/stable"
+	var tracking snapChannel
+	if b, err := ioutil.ReadFile(filepath.Join(snapDir, "channel.json")); err == nil {
+		if err := json.Unmarshal(b, &tracking); err == nil {
+			channelName = tracking.Channel
+		}
+	}
+	revision := snapDirRevision(snapDir)
+	channelMap := []map[string]interface{}{}
+	for _, architecture := range info.Architectures {
+		channelMap = append(channelMap, map[string]interface{}{
+			"architecture": architecture,
+			"channel":      channelName,
+			"revision":     revision,
+		})
+	}
+	writeStoreJSON(w, map[string]interface{}{
+		"channel-map": channelMap,
+	})
+}
+
+// snapDeclaration serves /v2/assertions/snap-declaration/16/<snap-id>.
+func (ms *mockStore) snapDeclaration(w http.ResponseWriter, r *http.Request) {
+	snapID := strings.TrimPrefix(r.URL.Path, "/v2/assertions/snap-declaration/16/")
+	snapDir, err := ms.snapDirByID(snapID)
+	if err != nil {
+		writeStoreError(w, http.StatusNotFound, fmt.Errorf("no snap-declaration for %s", snapID))
+		return
+	}
+	ref, headers := readDeclHeaders(snapDir)
+	if revision := readRevision(snapDir, "decl-revision"); revision >= 0 {
+		headers["revision"] = strconv.Itoa(revision)
+	}
+
+	ms.mu.Lock()
+	decl, err := ms.am.signSnapDecl(ref.PublisherID, headers)
+	ms.mu.Unlock()
+	if err != nil {
+		writeStoreError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", asserts.MediaType)
+	w.Write(asserts.Encode(decl))
+}
+
+func (ms *mockStore) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dev/api/snaps/info/", ms.snapInfo)
+	mux.HandleFunc("/api/v2/snaps/", ms.snapRevisions)
+	mux.HandleFunc("/v2/assertions/snap-declaration/16/", ms.snapDeclaration)
+	return mux
+}
+
+// Operations
+
+func mockStoreOp(param *json.RawMessage) error {
+	var params struct {
+		// Dir is the directory with the snap directories to serve
+		Dir string `json:"dir"`
+		// Addr is the address to listen on, by default a free port
+		// on localhost
+		Addr string `json:"addr"`
+	}
+	if err := json.Unmarshal([]byte(*param), &params); err != nil {
+		return err
+	}
+	if params.Addr == "" {
+		params.Addr = "127.0.0.1:0"
+	}
+
+	l, err := net.Listen("tcp", params.Addr)
+	if err != nil {
+		return err
+	}
+	// the store URL to point fetching at
+	fmt.Printf("http://%s/\n", l.Addr())
+	return http.Serve(l, newMockStore(params.Dir).handler())
+}
@@ -0,0 +1,193 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/snapcore/snapd/store/tooling"
+)
+
+// newTestMockStore serves the snap directories under dir with the
+// mock-store, returning a devStore and a tooling store pointed at it.
+func newTestMockStore(t *testing.T, dir string) (*devStore, *tooling.ToolingStore) {
+	t.Helper()
+	srv := httptest.NewServer(newMockStore(dir).handler())
+	t.Cleanup(srv.Close)
+	ds, err := newDevStore(srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// picked up by the tooling store
+	t.Setenv("UBUNTU_STORE_URL", srv.URL)
+	tsto, err := tooling.NewToolingStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ds, tsto
+}
+
+const mockStoreFooYaml = `name: foo
+version: 1
+architectures: [amd64]
+plugs:
+  x11:
+`
+
+func writeMockStoreDir(t *testing.T) {
+	t.Helper()
+	writeSnapDir(t, "store/foo", "pub", mockStoreFooYaml,
+		`{"x11": {"allow-auto-connection": "true"}}`,
+		`{"network": {"allow-installation": "false"}}`)
+	writeTestFile(t, "store/foo/revision", "5\n")
+	writeTestFile(t, "store/foo/decl-revision", "3\n")
+	// a local snap, without revisions
+	writeSnapDir(t, "store/bar", "pub", "name: bar\nversion: 1\n",
+		`{"home": {"allow-connection": "true"}}`, "")
+}
+
+func TestMockStoreFetch(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeMockStoreDir(t)
+	ds, tsto := newTestMockStore(t, "store")
+
+	params := &fetchParams{Meta: true, Decls: true, Architecture: "amd64"}
+	_, err := fetchAll(ds, tsto, []fetchSnap{{Name: "foo"}, {Name: "bar"}}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"foo", "bar"} {
+		ref, err := readRef(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &snapRef{SnapName: name, SnapID: name + "-id", PublisherID: "pub"}
+		if !reflect.DeepEqual(ref, want) {
+			t.Errorf("expected ref %+v, got %+v", want, ref)
+		}
+		if got, want := readFile(t, filepath.Join(name, "snap.yaml")), readFile(t, filepath.Join("store", name, "snap.yaml")); got != want {
+			t.Errorf("%s: expected snap.yaml %q, got %q", name, want, got)
+		}
+		for _, what := range []string{"plugs.json", "slots.json"} {
+			want, err := loadJSON(filepath.Join("store", name, what))
+			if os.IsNotExist(err) {
+				if _, err := os.Stat(filepath.Join(name, what)); !os.IsNotExist(err) {
+					t.Errorf("%s: unexpected %s: %v", name, what, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadJSON(filepath.Join(name, what))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: expected %s %v, got %v", name, what, want, got)
+			}
+		}
+	}
+	for _, tc := range []struct {
+		name, what string
+		revision   int
+	}{
+		{"foo", "revision", 5},
+		{"foo", "decl-revision", 3},
+		// local snaps are served as revision 1
+		{"bar", "revision", 1},
+		{"bar", "decl-revision", 0},
+	} {
+		if rev := readRevision(tc.name, tc.what); rev != tc.revision {
+			t.Errorf("%s: expected %s %d, got %d", tc.name, tc.what, tc.revision, rev)
+		}
+	}
+}
+
+func TestMockStoreRevisions(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeMockStoreDir(t)
+	ds, _ := newTestMockStore(t, "store")
+
+	for _, revision := range []string{"latest", "5"} {
+		revno, snapYaml, err := ds.revisionMeta("foo", revision)
+		if err != nil {
+			t.Fatalf("%s: %v", revision, err)
+		}
+		if revno != 5 || snapYaml != mockStoreFooYaml {
+			t.Errorf("%s: unexpected revision %d with snap.yaml %q", revision, revno, snapYaml)
+		}
+	}
+	_, _, err := ds.revisionMeta("foo", "4")
+	if err == nil || !strings.Contains(err.Error(), "404") || !strings.Contains(err.Error(), "no revision 4 of foo") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+
+	revno, err := ds.channelRevision("foo", "latest/stable", "amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revno != 5 {
+		t.Errorf("expected revision 5 on latest/stable, got %d", revno)
+	}
+	_, err = ds.channelRevision("foo", "latest/stable", "arm64")
+	if err == nil {
+		t.Errorf("expected foo not to be released for arm64")
+	}
+}
+
+func TestMockStoreNotFound(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeMockStoreDir(t)
+	ds, tsto := newTestMockStore(t, "store")
+
+	var v map[string]interface{}
+	for _, path := range []string{
+		"dev/api/snaps/info/missing",
+		"dev/api/snaps/info/foo.snap",
+		"api/v2/snaps/missing/revisions/latest",
+		"api/v2/snaps/missing/channel-map",
+		"api/v2/snaps/foo/other",
+		"v2/assertions/snap-declaration/16/missing-id",
+	} {
+		err := ds.get(path, &v)
+		if err == nil || !strings.Contains(err.Error(), "404") {
+			t.Errorf("%s: expected not found error, got %v", path, err)
+		}
+	}
+
+	if _, err := ds.snapRef("missing"); err == nil {
+		t.Errorf("expected no snap ref for a missing snap")
+	}
+	// a snap directory whose snap-id the store does not know
+	writeSnapDir(t, "other", "pub", "name: other\nversion: 1\n", "", "")
+	if _, err := fetchDecl(tsto, nil, "other", false); err == nil {
+		t.Errorf("expected no snap-declaration for an unknown snap-id")
+	}
+	if _, err := os.Stat(filepath.Join("other", "decl-revision")); !os.IsNotExist(err) {
+		t.Errorf("unexpected decl-revision: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+type overGrantSimulation struct {
+	scenarioParams
+
+	// Snap is the snap whose declaration rules change
+	Snap string `json:"snap"`
+	// ProposedPlugs and ProposedSlots are paths to the proposed
+	// plugs.json and slots.json, either can be omitted to keep the
+	// current rules
+	ProposedPlugs string `json:"proposed-plugs"`
+	ProposedSlots string `json:"proposed-slots"`
+
+	Snaps []string `json:"snaps"`
+}
+
+type verdictChange struct {
+	Before *pairVerdict `json:"before"`
+	After  *pairVerdict `json:"after"`
+}
+
+type overGrantResult struct {
+	// Evaluated is the number of plug/slot pairs evaluated
+	Evaluated int `json:"evaluated"`
+
+	Changes []verdictChange `json:"changes"`
+}
+
+func (s *oneshotSimulation) simulateOverGrant(params *overGrantSimulation) (*overGrantResult, error) {
+	snaps := append([]string{params.Snap}, params.Snaps...)
+	c, err := s.setupCorpus(&params.scenarioParams, snaps)
+	if err != nil {
+		return nil, err
+	}
+	snapName := c.sc.infos[params.Snap].SnapName()
+
+	ref, headers := readDeclHeaders(params.Snap)
+	for side, fn := range map[string]string{
+		"plugs": params.ProposedPlugs,
+		"slots": params.ProposedSlots,
+	} {
+		if fn == "" {
+			continue
+		}
+		rules, err := loadJSON(fn)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load proposed %s rules: %v", side, err)
+		}
+		headers[side] = rules
+	}
+	proposed, err := s.signSnapDecl(ref.PublisherID, headers)
+	if err != nil {
+		return nil, fmt.Errorf("processing proposed rules: %v", err)
+	}
+
+	res := &overGrantResult{}
+	repo := s.mgr.Repository()
+	// all the plugs of the interfaces of the snap are affected, also
+	// ones not connecting to it, as a broadened rule of the snap can
+	// make their auto-connection ambiguous
+	seen := make(map[string]bool)
+	var ifaces []string
+	addIface := func(iface string) {
+		if !seen[iface] {
+			seen[iface] = true
+			ifaces = append(ifaces, iface)
+		}
+	}
+	for _, plug := range repo.Plugs(snapName) {
+		addIface(plug.Interface)
+	}
+	for _, slot := range repo.Slots(snapName) {
+		addIface(slot.Interface)
+	}
+	for _, iface := range ifaces {
+		slots := repo.AllSlots(iface)
+		for _, plug := range repo.AllPlugs(iface) {
+			for _, slot := range slots {
+				before, err := c.evaluatePairWith(plug, slot, slots, "", nil)
+				if err != nil {
+					return nil, err
+				}
+				after, err := c.evaluatePairWith(plug, slot, slots, snapName, proposed)
+				if err != nil {
+					return nil, err
+				}
+				res.Evaluated++
+				if before.Verdict != after.Verdict || before.AllowConnection != after.AllowConnection || before.AllowAutoConnection != after.AllowAutoConnection {
+					res.Changes = append(res.Changes, verdictChange{
+						Before: before,
+						After:  after,
+					})
+				}
+			}
+		}
+	}
+	sort.Slice(res.Changes, func(i, j int) bool {
+		v1 := res.Changes[i].After
+		v2 := res.Changes[j].After
+		if v1.Interface != v2.Interface {
+			return v1.Interface < v2.Interface
+		}
+		return connRefLess(&v1.PlugRef, &v1.SlotRef, &v2.PlugRef, &v2.SlotRef)
+	})
+	return res, nil
+}
+
+// Operations
+
+func overGrant(param *json.RawMessage) error {
+	var params overGrantSimulation
+	if err := json.Unmarshal([]byte(*param), &params); err != nil {
+		return err
+	}
+
+	sim := oneshotSimulation{}
+	sim.setup(params.Classic)
+	res, err := sim.simulateOverGrant(&params)
+	if err != nil {
+		return outputError("json", err)
+	}
+	sim.finish()
+
+	b, err := json.Marshal(res)
+	noerror(err)
+	fmt.Println(string(b))
+	return nil
+}
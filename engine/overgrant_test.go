@@ -0,0 +1,83 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/snapcore/snapd/interfaces"
+)
+
+func TestOverGrantAmbiguity(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeSnapDir(t, "consumer", "pub1", fmt.Sprintf(contentPlugYaml, "consumer"), "", "")
+	writeSnapDir(t, "provider1", "pub1", fmt.Sprintf(contentSlotYaml, "provider1"), "", "")
+	writeSnapDir(t, "provider2", "pub2", fmt.Sprintf(contentSlotYaml, "provider2"), "", "")
+	// provider2 would auto-connect to plugs of any publisher
+	writeTestFile(t, "proposed-slots.json", `{"content": {"allow-auto-connection": "true"}}`)
+
+	sim := newTestSimulation(t, false)
+	res, err := sim.simulateOverGrant(&overGrantSimulation{
+		Snap:          "provider2",
+		ProposedSlots: "proposed-slots.json",
+		Snaps:         []string{"consumer", "provider1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Evaluated != 2 {
+		t.Errorf("expected 2 pairs evaluated, got %d", res.Evaluated)
+	}
+	if len(res.Changes) != 2 {
+		t.Fatalf("expected 2 changes, got %+v", res.Changes)
+	}
+
+	// consumer loses its auto-connection to provider1, which is not
+	// the changed snap
+	ch := res.Changes[0]
+	provider1 := interfaces.SlotRef{Snap: "provider1", Name: "data"}
+	provider2 := interfaces.SlotRef{Snap: "provider2", Name: "data"}
+	if ch.After.SlotRef != provider1 {
+		t.Fatalf("expected a change for %v, got %v", provider1, ch.After.SlotRef)
+	}
+	if ch.Before.Verdict != verdictAuto || ch.After.Verdict != verdictManual {
+		t.Errorf("expected auto -> manual, got %s -> %s", ch.Before.Verdict, ch.After.Verdict)
+	}
+	if want := []interfaces.SlotRef{provider1, provider2}; !reflect.DeepEqual(ch.After.Candidates, want) {
+		t.Errorf("expected candidates %v, got %v", want, ch.After.Candidates)
+	}
+	if ch.After.Reason != "ambiguous auto-connection, 2 candidate slots" {
+		t.Errorf("unexpected reason %q", ch.After.Reason)
+	}
+
+	// and the grant for provider2 is masked by the ambiguity
+	ch = res.Changes[1]
+	if ch.After.SlotRef != provider2 {
+		t.Fatalf("expected a change for %v, got %v", provider2, ch.After.SlotRef)
+	}
+	if ch.Before.Verdict != verdictManual || ch.After.Verdict != verdictManual {
+		t.Errorf("expected manual -> manual, got %s -> %s", ch.Before.Verdict, ch.After.Verdict)
+	}
+	if ch.Before.AllowAutoConnection || !ch.After.AllowAutoConnection {
+		t.Errorf("expected the auto-connection grant to be broadened")
+	}
+}
@@ -0,0 +1,557 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/arch"
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snapdir"
+	"github.com/snapcore/snapd/snap/snapfile"
+	"github.com/snapcore/snapd/strutil"
+)
+
+var snapdSnapYaml = `
+name: snapd
+version: 1
+type: snapd
+`
+
+var coreSnapYaml = `
+name: core
+version: 1
+type: os
+`
+
+var baseSnapYamlTemplate = `
+name: %s
+version: 1
+type: base
+`
+
+// systemFlavour describes the snaps making up the system in a simulation.
+type systemFlavour struct {
+	// systemSnap is the snap carrying the implicit system slots
+	systemSnap string
+	// base is the base to set in the model, if any
+	base  string
+	yamls []string
+}
+
+func systemFlavourFor(flavour string) (*systemFlavour, error) {
+	switch flavour {
+	case "", "snapd":
+		return &systemFlavour{
+			systemSnap: "snapd",
+			yamls:      []string{snapdSnapYaml},
+		}, nil
+	case "core":
+		return &systemFlavour{
+			systemSnap: "core",
+			yamls:      []string{coreSnapYaml},
+		}, nil
+	case "core16", "core18", "core20", "core22", "core24":
+		return &systemFlavour{
+			systemSnap: "snapd",
+			base:       flavour,
+			yamls:      []string{snapdSnapYaml, fmt.Sprintf(baseSnapYamlTemplate, flavour)},
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported system flavour: %s", flavour)
+}
+
+// supportedArchitectures are the architectures snapd supports, by their
+// Debian names.
+var supportedArchitectures = []string{
+	"amd64",
+	"arm64",
+	"armhf",
+	"i386",
+	"ppc64",
+	"ppc64el",
+	"riscv64",
+	"s390x",
+}
+
+// scenarioParams are the parameters common to the simulations over
+// a set of snap directories.
+type scenarioParams struct {
+	Classic bool `json:"classic"`
+	// System is the flavour of the system snaps: snapd (the default),
+	// core or coreNN (a coreNN base plus snapd)
+	System string `json:"system"`
+	// Architecture defaults to amd64
+	Architecture string `json:"architecture"`
+	// BaseDeclaration is the path of a file with a base-declaration
+	// to use instead of the builtin one
+	BaseDeclaration string `json:"base-declaration"`
+	// ValidationSets are paths of files with validation-set assertions
+	// to check the snaps against
+	ValidationSets []string `json:"validation-sets"`
+	// ValidationMode is monitor (the default), reporting the snaps
+	// violating the validation sets, or enforce, refusing them
+	ValidationMode string `json:"validation-mode"`
+
+	// Deterministic pins the mock assertion timestamps and sorts
+	// all the results
+	Deterministic bool `json:"deterministic"`
+	// Format is the output format: json (the default), dot or mermaid
+	Format string `json:"format"`
+
+	// Brand and Model default to defaultBrand and defaultModel
+	Brand string `json:"brand"`
+	Model string `json:"model"`
+	Store string `json:"store"`
+}
+
+// the model of the simulated device if none is given, as the ifacetool
+// --model default
+const (
+	defaultBrand = "brand"
+	defaultModel = "model"
+)
+
+type mockedSnap struct {
+	info *snap.Info
+	decl *asserts.SnapDeclaration
+}
+
+// scenario tracks the snaps of a simulation and their installation.
+type scenario struct {
+	modelAs *asserts.Model
+	storeAs *asserts.Store
+	flavour *systemFlavour
+
+	// snaps are the snap directories or .snap files in the order given,
+	// without repetitions
+	snaps []string
+	// infos are the snap infos as read from the snaps
+	infos map[string]*snap.Info
+
+	installed map[string]*mockedSnap
+
+	// validationSets are the validation sets the snaps are checked
+	// against, enforced or only monitored
+	validationSets    []*asserts.ValidationSet
+	enforceValidation bool
+}
+
+// setupScenario sets up the model, the declarations and the system snaps
+// for the given snap directories and initializes the interface manager.
+// The snaps themselves are then installed with installSnap.
+func (s *oneshotSimulation) setupScenario(params *scenarioParams, snaps []string) (*scenario, error) {
+	flavour, err := systemFlavourFor(params.System)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.BaseDeclaration != "" {
+		if err := useBaseDeclaration(params.BaseDeclaration); err != nil {
+			return nil, err
+		}
+	}
+
+	if params.Deterministic {
+		s.pinTimestamps()
+	}
+
+	sc := &scenario{
+		flavour:   flavour,
+		infos:     make(map[string]*snap.Info, len(snaps)),
+		installed: make(map[string]*mockedSnap, len(snaps)),
+	}
+	switch params.ValidationMode {
+	case "", "monitor":
+	case "enforce":
+		sc.enforceValidation = true
+	default:
+		return nil, fmt.Errorf("unknown validation mode %q", params.ValidationMode)
+	}
+	sc.validationSets, err = readValidationSets(params.ValidationSets)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range snaps {
+		if sc.infos[name] != nil {
+			continue
+		}
+		snapInfo, err := readSnapInfo(name)
+		if err != nil {
+			return nil, fmt.Errorf("processing snap %s: %v", name, err)
+		}
+		sc.infos[name] = snapInfo
+		sc.snaps = append(sc.snaps, name)
+	}
+
+	architecture := params.Architecture
+	if architecture == "" {
+		architecture = "amd64"
+	}
+	if !strutil.ListContains(supportedArchitectures, architecture) {
+		return nil, fmt.Errorf("unsupported architecture: %s (expected one of %s)", architecture, strings.Join(supportedArchitectures, ", "))
+	}
+	// this affects interfaces and system slots that vary by architecture
+	arch.SetArchitecture(arch.ArchitectureType(architecture))
+
+	brand := params.Brand
+	if brand == "" {
+		brand = defaultBrand
+	}
+	model := params.Model
+	if model == "" {
+		model = defaultModel
+	}
+	modelHdrs := map[string]interface{}{
+		"authority-id": brand,
+		"brand-id":     brand,
+		"model":        model,
+		"architecture": architecture,
+	}
+	if params.Store != "" {
+		modelHdrs["store"] = params.Store
+	}
+	if flavour.base != "" {
+		modelHdrs["base"] = flavour.base
+	}
+	// kernel and gadget snaps that are part of the scenario go in the model
+	for _, name := range sc.snaps {
+		snapInfo := sc.infos[name]
+		switch snapInfo.Type() {
+		case snap.TypeKernel:
+			modelHdrs["kernel"] = snapInfo.SnapName()
+		case snap.TypeGadget:
+			modelHdrs["gadget"] = snapInfo.SnapName()
+		}
+	}
+	sc.modelAs = s.mockModel(modelHdrs)
+	if params.Store != "" {
+		sc.storeAs = s.mockStore(s.state, params.Store, nil)
+	}
+
+	// Add declarations
+	for _, name := range sc.snaps {
+		ref, d := readDeclHeaders(name)
+		if err := s.mockSnapDecl(ref.PublisherID, d); err != nil {
+			return nil, fmt.Errorf("processing snap %s rules: %v", name, err)
+		}
+	}
+
+	// Add the system snaps, the ones that are part of the scenario
+	// are taken from their directories.
+	for _, yamlText := range flavour.yamls {
+		snapInfo, err := snap.InfoFromSnapYaml([]byte(yamlText))
+		noerror(err)
+		name := snapInfo.SnapName()
+		meta := &snapMeta{snapYaml: []byte(yamlText)}
+		if sc.infos[name] != nil {
+			meta, err = readSnapMeta(name)
+			noerror(err)
+		}
+		snapInfo, snapDecl, err := s.mockSnap(meta)
+		if err != nil {
+			return nil, fmt.Errorf("processing snap %s: %v", name, err)
+		}
+		sc.installed[name] = &mockedSnap{info: snapInfo, decl: snapDecl}
+	}
+
+	// Initialize the manager. This registers the system snaps.
+	s.manager()
+
+	return sc, nil
+}
+
+// installSnap adds the snap from the given snap directory or .snap file
+// to the state and to the interface repository, unless it was already
+// installed.
+func (s *oneshotSimulation) installSnap(sc *scenario, name string) (*mockedSnap, error) {
+	if mocked := sc.installed[name]; mocked != nil {
+		return mocked, nil
+	}
+	meta, err := readSnapMeta(name)
+	noerror(err)
+	snapInfo, snapDecl, err := s.mockSnap(meta)
+	if err != nil {
+		return nil, fmt.Errorf("processing snap %s: %v", name, err)
+	}
+
+	snapAppSet, err := interfaces.NewSnapAppSet(snapInfo, nil)
+	if err != nil {
+		return nil, fmt.Errorf("processing snap %s: %v", name, err)
+	}
+
+	err = s.mgr.Repository().AddAppSet(snapAppSet)
+	if err != nil {
+		return nil, fmt.Errorf("processing snap %s: %v", snapInfo.SnapName(), err)
+	}
+
+	mocked := &mockedSnap{info: snapInfo, decl: snapDecl}
+	sc.installed[name] = mocked
+	return mocked, nil
+}
+
+// systemSlots returns the implicit slots of the system snap.
+func (sc *scenario) systemSlots(repo *interfaces.Repository) []side {
+	var slots []side
+	for _, slot := range repo.Slots(sc.flavour.systemSnap) {
+		slots = append(slots, side{
+			Interface: slot.Interface,
+			Name:      slot.Name,
+		})
+	}
+	return slots
+}
+
+// isSnapFile returns whether the snap input is a .snap file instead of a
+// snap directory.
+func isSnapFile(name string) bool {
+	return strings.HasSuffix(name, ".snap")
+}
+
+// snapMeta is the metadata of a snap input.
+type snapMeta struct {
+	snapYaml []byte
+	// gadgetYaml is set for gadget snaps that have one
+	gadgetYaml []byte
+	// hooks are the names of the hooks in meta/hooks
+	hooks []string
+	// metaDir is the meta/ tree of a snap directory, if it has one
+	metaDir string
+	// snapFile is set for .snap files
+	snapFile string
+}
+
+// readSnapMeta reads the metadata from a snap directory or directly
+// from a .snap file. Besides snap.yaml a snap directory can hold the
+// list of hook names in a hooks file, one per line, or the rest of the
+// meta/ tree of the snap under meta/.
+func readSnapMeta(name string) (*snapMeta, error) {
+	meta := &snapMeta{}
+	if isSnapFile(name) {
+		container, err := snapfile.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		meta.snapYaml, err = container.ReadFile("meta/snap.yaml")
+		if err != nil {
+			return nil, err
+		}
+		if b, err := container.ReadFile("meta/gadget.yaml"); err == nil {
+			meta.gadgetYaml = b
+		}
+		if hooks, err := container.ListDir("meta/hooks"); err == nil {
+			meta.hooks = hooks
+		}
+		meta.snapFile = name
+		return meta, nil
+	}
+	b, err := ioutil.ReadFile(filepath.Join(name, "snap.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	meta.snapYaml = b
+	metaDir := filepath.Join(name, "meta")
+	if osutil.IsDirectory(metaDir) {
+		meta.metaDir = metaDir
+		if b, err := ioutil.ReadFile(filepath.Join(metaDir, "gadget.yaml")); err == nil {
+			meta.gadgetYaml = b
+		}
+		fis, err := ioutil.ReadDir(filepath.Join(metaDir, "hooks"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, fi := range fis {
+			meta.hooks = append(meta.hooks, fi.Name())
+		}
+	} else {
+		b, err := ioutil.ReadFile(filepath.Join(name, "hooks"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		meta.hooks = strings.Fields(string(b))
+	}
+	if meta.gadgetYaml == nil {
+		if b, err := ioutil.ReadFile(filepath.Join(name, "gadget.yaml")); err == nil {
+			meta.gadgetYaml = b
+		}
+	}
+	return meta, nil
+}
+
+// writeMetaTree writes the meta/ tree of the snap under dir, the hooks
+// are written as empty executables.
+func writeMetaTree(meta *snapMeta, dir string) error {
+	metaDir := filepath.Join(dir, "meta")
+	if meta.metaDir != "" {
+		err := filepath.Walk(meta.metaDir, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(meta.metaDir, path)
+			noerror(err)
+			dest := filepath.Join(metaDir, rel)
+			if fi.IsDir() {
+				return os.MkdirAll(dest, 0755)
+			}
+			return osutil.CopyFile(path, dest, osutil.CopyFlagOverwrite|osutil.CopyFlagPreserveAll)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(metaDir, 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(metaDir, "snap.yaml"), meta.snapYaml, 0644); err != nil {
+		return err
+	}
+	if meta.gadgetYaml != nil {
+		if err := ioutil.WriteFile(filepath.Join(metaDir, "gadget.yaml"), meta.gadgetYaml, 0644); err != nil {
+			return err
+		}
+	}
+	if len(meta.hooks) == 0 {
+		return nil
+	}
+	hooksDir := filepath.Join(metaDir, "hooks")
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return err
+	}
+	for _, hook := range meta.hooks {
+		hookFile := filepath.Join(hooksDir, hook)
+		if osutil.FileExists(hookFile) {
+			continue
+		}
+		if err := ioutil.WriteFile(hookFile, nil, 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveRef returns the snap ids and the snap directory with the
+// declaration rules for a snap input. For .snap files the directory
+// named after the snap is used if there is one, otherwise local ids
+// are made up and there are no rules.
+func resolveRef(name string) (*snapRef, string) {
+	if !isSnapFile(name) {
+		ref, err := readRef(name)
+		noerror(err)
+		return ref, name
+	}
+	info, err := readSnapInfo(name)
+	noerror(err)
+	snapName := info.SnapName()
+	if ref, err := readRef(snapName); err == nil {
+		return ref, snapName
+	}
+	return &snapRef{
+		SnapName:    snapName,
+		SnapID:      "local-" + snapName,
+		PublisherID: "local",
+	}, ""
+}
+
+// readDeclHeaders reads the snap-declaration headers for the snap
+// directory or .snap file.
+func readDeclHeaders(name string) (*snapRef, map[string]interface{}) {
+	ref, dir := resolveRef(name)
+	d := map[string]interface{}{
+		"snap-name":    ref.SnapName,
+		"snap-id":      ref.SnapID,
+		"publisher-id": ref.PublisherID,
+	}
+	if dir == "" {
+		return ref, d
+	}
+	if plugs, err := loadJSON(filepath.Join(dir, "plugs.json")); !os.IsNotExist(err) {
+		noerror(err)
+		d["plugs"] = plugs
+	}
+	if slots, err := loadJSON(filepath.Join(dir, "slots.json")); !os.IsNotExist(err) {
+		noerror(err)
+		d["slots"] = slots
+	}
+	return ref, d
+}
+
+func readSnapInfo(name string) (*snap.Info, error) {
+	if isSnapFile(name) {
+		container, err := snapfile.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		// this adds the implicit hooks as well
+		return snap.ReadInfoFromSnapFile(container, nil)
+	}
+	meta, err := readSnapMeta(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(meta.hooks) == 0 {
+		return snap.InfoFromSnapYaml(meta.snapYaml)
+	}
+	// build the info from the meta/ tree for the implicit hooks
+	dir, err := ioutil.TempDir("", "ifacetool-snap")
+	noerror(err)
+	defer os.RemoveAll(dir)
+	if err := writeMetaTree(meta, dir); err != nil {
+		return nil, err
+	}
+	return snap.ReadInfoFromSnapFile(snapdir.New(dir), nil)
+}
+
+// Operations
+
+func readSnapOp(param *json.RawMessage) error {
+	var params struct {
+		Snap string `json:"snap"`
+	}
+	if err := json.Unmarshal([]byte(*param), &params); err != nil {
+		return err
+	}
+	if !isSnapFile(params.Snap) {
+		return fmt.Errorf("not a .snap file: %s", params.Snap)
+	}
+
+	meta, err := readSnapMeta(params.Snap)
+	if err != nil {
+		return err
+	}
+	res := map[string]interface{}{
+		"snap-yaml": string(meta.snapYaml),
+		"hooks":     meta.hooks,
+	}
+	if meta.gadgetYaml != nil {
+		res["gadget-yaml"] = string(meta.gadgetYaml)
+	}
+	b, err := json.Marshal(res)
+	noerror(err)
+	fmt.Println(string(b))
+	return nil
+}
@@ -0,0 +1,42 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSetupScenarioArchitecture(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeSnapDir(t, "foo", "pub1", fmt.Sprintf(contentPlugYaml, "foo"), "", "")
+
+	sim := newTestSimulation(t, false)
+	if _, err := sim.setupScenario(&scenarioParams{Architecture: "arm64"}, []string{"foo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sim = newTestSimulation(t, false)
+	_, err := sim.setupScenario(&scenarioParams{Architecture: "arm46"}, []string{"foo"})
+	if err == nil || !strings.HasPrefix(err.Error(), "unsupported architecture: arm46 (expected one of amd64, arm64,") {
+		t.Errorf("expected unsupported architecture error, got %v", err)
+	}
+}
@@ -27,11 +27,14 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/tomb.v2"
 
+	"github.com/snapcore/snapd/arch"
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/dirs"
@@ -65,6 +68,8 @@ type assertsMock struct {
 	db           *asserts.Database
 	storeSigning *assertstest.StoreStack
 	st           *state.State
+
+	pinnedTime time.Time
 }
 
 func (am *assertsMock) setupAsserts(st *state.State) {
@@ -85,6 +90,38 @@ func (am *assertsMock) setupAsserts(st *state.State) {
 	st.Unlock()
 }
 
+// pinnedTimestamp is the timestamp of all the mock assertions in
+// deterministic mode.
+var pinnedTimestamp = time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// pinTimestamps makes all the mock assertions use pinnedTimestamp instead
+// of the current time, across runs. The mock store keys are valid only
+// since they were generated, so from then on assertions are signed with
+// a store key valid since pinnedTimestamp instead.
+func (am *assertsMock) pinTimestamps() {
+	am.pinnedTime = pinnedTimestamp
+
+	privKey, _ := assertstest.GenerateKey(752)
+	err := am.storeSigning.ImportKey(privKey)
+	noerror(err)
+	pinnedKey := assertstest.NewAccountKey(am.storeSigning.RootSigning, am.storeSigning.TrustedAccount, map[string]interface{}{
+		"name":  "pinned",
+		"since": pinnedTimestamp.Format(time.RFC3339),
+	}, privKey.PublicKey(), "")
+	err = am.storeSigning.Add(pinnedKey)
+	noerror(err)
+	err = am.db.Add(pinnedKey)
+	noerror(err)
+	am.storeSigning.KeyID = pinnedKey.PublicKeyID()
+}
+
+func (am *assertsMock) timestamp() string {
+	if !am.pinnedTime.IsZero() {
+		return am.pinnedTime.Format(time.RFC3339)
+	}
+	return time.Now().Format(time.RFC3339)
+}
+
 func (am *assertsMock) mockModel(extraHeaders map[string]interface{}) *asserts.Model {
 	modHeaders := map[string]interface{}{
 		"type":         "model",
@@ -92,20 +129,24 @@ func (am *assertsMock) mockModel(extraHeaders map[string]interface{}) *asserts.M
 		"gadget":       "gadget",
 		"kernel":       "kernel",
 		"architecture": "amd64",
-		"timestamp":    time.Now().Format(time.RFC3339),
+		"timestamp":    am.timestamp(),
 	}
 	model := assertstest.FakeAssertion(modHeaders, extraHeaders).(*asserts.Model)
 	snapstatetest.MockDeviceModel(model)
 	return model
 }
 
-func (am *assertsMock) mockSnapDecl(publisher string, extraHeaders map[string]interface{}) error {
+// signSnapDecl signs a snap-declaration with the mock store keys,
+// adding the publisher account if needed, without adding the
+// declaration to the database.
+func (am *assertsMock) signSnapDecl(publisher string, extraHeaders map[string]interface{}) (*asserts.SnapDeclaration, error) {
 	_, err := am.db.Find(asserts.AccountType, map[string]string{
 		"account-id": publisher,
 	})
 	if errors.Is(err, &asserts.NotFoundError{}) {
 		acct := assertstest.NewAccount(am.storeSigning, publisher, map[string]interface{}{
 			"account-id": publisher,
+			"timestamp":  am.timestamp(),
 		}, "")
 		err = am.db.Add(acct)
 	}
@@ -113,7 +154,7 @@ func (am *assertsMock) mockSnapDecl(publisher string, extraHeaders map[string]in
 
 	headers := map[string]interface{}{
 		"series":    "16",
-		"timestamp": time.Now().Format(time.RFC3339),
+		"timestamp": am.timestamp(),
 	}
 	for k, v := range extraHeaders {
 		headers[k] = v
@@ -121,11 +162,19 @@ func (am *assertsMock) mockSnapDecl(publisher string, extraHeaders map[string]in
 
 	fnum, err := asserts.SuggestFormat(asserts.SnapDeclarationType, headers, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	headers["format"] = strconv.Itoa(fnum)
 
 	snapDecl, err := am.storeSigning.Sign(asserts.SnapDeclarationType, headers, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return snapDecl.(*asserts.SnapDeclaration), nil
+}
+
+func (am *assertsMock) mockSnapDecl(publisher string, extraHeaders map[string]interface{}) error {
+	snapDecl, err := am.signSnapDecl(publisher, extraHeaders)
 	if err != nil {
 		return err
 	}
@@ -136,11 +185,11 @@ func (am *assertsMock) mockSnapDecl(publisher string, extraHeaders map[string]in
 	return nil
 }
 
-func (am *assertsMock) mockStore(st *state.State, storeID string, extraHeaders map[string]interface{}) {
+func (am *assertsMock) mockStore(st *state.State, storeID string, extraHeaders map[string]interface{}) *asserts.Store {
 	headers := map[string]interface{}{
 		"store":       storeID,
 		"operator-id": am.storeSigning.AuthorityID,
-		"timestamp":   time.Now().Format(time.RFC3339),
+		"timestamp":   am.timestamp(),
 	}
 	for k, v := range extraHeaders {
 		headers[k] = v
@@ -151,6 +200,7 @@ func (am *assertsMock) mockStore(st *state.State, storeID string, extraHeaders m
 	defer st.Unlock()
 	err = assertstate.Add(st, storeAs)
 	noerror(err)
+	return storeAs.(*asserts.Store)
 }
 
 // oneshotSimulation simulate one interface manager behavior at a time,
@@ -256,11 +306,11 @@ func (s *oneshotSimulation) hookManager() *hookstate.HookManager {
 	return mgr
 }
 
-func (s *oneshotSimulation) mockSnap(yamlText string) (*snap.Info, *asserts.SnapDeclaration, error) {
+func (s *oneshotSimulation) mockSnap(meta *snapMeta) (*snap.Info, *asserts.SnapDeclaration, error) {
 	sideInfo := &snap.SideInfo{
 		Revision: snap.R(1),
 	}
-	snapInfo, err := mockDiskSnap(yamlText, sideInfo)
+	snapInfo, err := mockDiskSnap(meta, sideInfo)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -304,11 +354,16 @@ func (s *oneshotSimulation) addSetupSnapSecurityChange(snapsup *snapstate.SnapSe
 	return change
 }
 
-var snapdSnapYaml = `
-name: snapd
-version: 1
-type: snapd
-`
+// checkArchitecture checks the snap supports the simulated architecture
+func checkArchitecture(info *snap.Info) error {
+	dpkgArch := arch.DpkgArchitecture()
+	for _, a := range info.Architectures {
+		if a == "all" || a == dpkgArch {
+			return nil
+		}
+	}
+	return fmt.Errorf("snap %q supported architectures (%s) are incompatible with this system (%s)", info.SnapName(), strings.Join(info.Architectures, ", "), dpkgArch)
+}
 
 func checkInstall(modelAs *asserts.Model, info *snap.Info, decl *asserts.SnapDeclaration) installation {
 	baseDecl := asserts.BuiltinBaseDeclaration()
@@ -328,28 +383,33 @@ func checkInstall(modelAs *asserts.Model, info *snap.Info, decl *asserts.SnapDec
 	if err != nil {
 		errStr = err.Error()
 	}
+	var archErrStr string
+	if err := checkArchitecture(info); err != nil {
+		archErrStr = err.Error()
+	}
 	return installation{
-		SnapName:      info.SnapName(),
-		Error:         errStr,
-		BadInterfaces: info.BadInterfaces,
+		SnapName:          info.SnapName(),
+		Error:             errStr,
+		ArchitectureError: archErrStr,
+		BadInterfaces:     info.BadInterfaces,
 	}
 }
 
 type autoConnectSimulation struct {
-	Classic bool `json:"classic"`
-
-	Brand string `json:"brand"`
-	Model string `json:"model"`
-	Store string `json:"store"`
+	scenarioParams
 
 	TargetSnap string   `json:"target-snap"`
 	Snaps      []string `json:"snaps"`
 }
 
 type installation struct {
-	SnapName      string            `json:"snap-name"`
-	Error         string            `json:"error"`
-	BadInterfaces map[string]string `json:"bad-interfaces,omitempty"`
+	SnapName          string            `json:"snap-name"`
+	Error             string            `json:"error"`
+	ArchitectureError string            `json:"architecture-error,omitempty"`
+	BadInterfaces     map[string]string `json:"bad-interfaces,omitempty"`
+	// ValidationErrors are the ways the snap violates the validation
+	// sets
+	ValidationErrors []string `json:"validation-errors,omitempty"`
 }
 
 type side struct {
@@ -376,6 +436,8 @@ type candidate struct {
 	SlotDynamicAttrs map[string]interface{} `json:"slot-dynamic-attrs"`
 
 	CheckError string `json:"check-error"`
+	// ConnectError is set if also a manual connection is not allowed
+	ConnectError string `json:"connect-error,omitempty"`
 
 	SlotsPerPlugAny bool `json:"slots-per-plug-any"`
 }
@@ -384,6 +446,13 @@ type autoConnectSimulationResult struct {
 	targetSnap string
 
 	Installing []installation `json:"installing"`
+	// MissingRequired are the snaps required by the validation sets
+	// that are missing
+	MissingRequired []missingSnap `json:"missing-required,omitempty"`
+
+	// SystemSnap is the snap carrying the implicit system slots
+	SystemSnap  string `json:"system-snap"`
+	SystemSlots []side `json:"system-slots"`
 
 	Plugs []side `json:"plugs"`
 	Slots []side `json:"slots"`
@@ -392,6 +461,44 @@ type autoConnectSimulationResult struct {
 
 	SlotCandidates map[string][]candidate `json:"slot-candidates"`
 	PlugCandidates map[string][]candidate `json:"plug-candidates"`
+
+	PlugArity []plugArity `json:"plug-arity"`
+	SlotArity []slotArity `json:"slot-arity"`
+}
+
+// plugArity is the arity analysis for a plug of the target snap.
+type plugArity struct {
+	Plug      string `json:"plug"`
+	Interface string `json:"interface"`
+	// SlotsPerPlug is the effective slots-per-plug from the
+	// declarations, 1 or *, empty without candidates
+	SlotsPerPlug string `json:"slots-per-plug"`
+	// Candidates are the slots the plug is allowed to auto-connect to
+	Candidates []interfaces.SlotRef `json:"candidates"`
+	// Ambiguous is set if auto-connection was skipped because of
+	// multiple candidates
+	Ambiguous bool `json:"ambiguous"`
+	// Resolutions are, for each candidate, the other candidates that
+	// would need to be removed or disallowed to auto-connect to it
+	Resolutions []arityResolution `json:"resolutions,omitempty"`
+}
+
+type arityResolution struct {
+	Slot     interfaces.SlotRef   `json:"slot"`
+	Disallow []interfaces.SlotRef `json:"disallow"`
+}
+
+// slotArity is the arity analysis for a slot of the target snap.
+type slotArity struct {
+	Slot      string `json:"slot"`
+	Interface string `json:"interface"`
+	// PlugsPerSlot is always * as snapd supports only that
+	PlugsPerSlot string `json:"plugs-per-slot"`
+	// Candidates are the plugs allowed to auto-connect to the slot
+	Candidates []interfaces.PlugRef `json:"candidates"`
+	// Skipped are the candidates that did not auto-connect to the slot,
+	// usually because of multiple candidates for them
+	Skipped []interfaces.PlugRef `json:"skipped,omitempty"`
 }
 
 func (r *autoConnectSimulationResult) debugAutoConnectCheck(cc *policy.ConnectCandidate, arity interfaces.SideArity, checkErr error) {
@@ -405,6 +512,9 @@ func (r *autoConnectSimulationResult) debugAutoConnectCheck(cc *policy.ConnectCa
 	cand.SlotDynamicAttrs = cc.Slot.DynamicAttrs()
 	if checkErr != nil {
 		cand.CheckError = checkErr.Error()
+		if err := cc.Check(); err != nil {
+			cand.ConnectError = err.Error()
+		}
 	} else {
 		cand.SlotsPerPlugAny = arity.SlotsPerPlugAny()
 	}
@@ -416,89 +526,94 @@ func (r *autoConnectSimulationResult) debugAutoConnectCheck(cc *policy.ConnectCa
 	}
 }
 
-func (s *oneshotSimulation) simulateAutoConnect(params *autoConnectSimulation) error {
-	modelHdrs := map[string]interface{}{
-		"authority-id": params.Brand,
-		"brand-id":     params.Brand,
-		"model":        params.Model,
+func sortSides(sides []side) {
+	sort.Slice(sides, func(i, j int) bool {
+		if sides[i].Name != sides[j].Name {
+			return sides[i].Name < sides[j].Name
+		}
+		return sides[i].Interface < sides[j].Interface
+	})
+}
+
+func connRefLess(plugRef1 *interfaces.PlugRef, slotRef1 *interfaces.SlotRef, plugRef2 *interfaces.PlugRef, slotRef2 *interfaces.SlotRef) bool {
+	if plugRef1.Snap != plugRef2.Snap {
+		return plugRef1.Snap < plugRef2.Snap
 	}
-	if params.Store != "" {
-		modelHdrs["store"] = params.Store
+	if plugRef1.Name != plugRef2.Name {
+		return plugRef1.Name < plugRef2.Name
 	}
-	modelAs := s.mockModel(modelHdrs)
-	if params.Store != "" {
-		s.mockStore(s.state, params.Store, nil)
+	if slotRef1.Snap != slotRef2.Snap {
+		return slotRef1.Snap < slotRef2.Snap
 	}
+	return slotRef1.Name < slotRef2.Name
+}
 
-	// Add a snapd snap.
-	s.mockSnap(snapdSnapYaml)
-
-	// Initialize the manager. This registers the system snap.
-	mgr := s.manager()
+func sortCandidates(cands []candidate) {
+	sort.Slice(cands, func(i, j int) bool {
+		c1 := &cands[i]
+		c2 := &cands[j]
+		if c1.PlugRef == c2.PlugRef && c1.SlotRef == c2.SlotRef {
+			return c1.CheckError < c2.CheckError
+		}
+		return connRefLess(&c1.PlugRef, &c1.SlotRef, &c2.PlugRef, &c2.SlotRef)
+	})
+}
 
-	snaps := params.Snaps
-	snaps = append(snaps, params.TargetSnap)
-	seen := make(map[string]bool, len(snaps))
+// sort sorts all the result slices, maps are output with sorted
+// keys already
+func (r *autoConnectSimulationResult) sort() {
+	sortSides(r.SystemSlots)
+	sortSides(r.Plugs)
+	sortSides(r.Slots)
+	sort.Slice(r.Connections, func(i, j int) bool {
+		c1 := &r.Connections[i]
+		c2 := &r.Connections[j]
+		return connRefLess(&c1.PlugRef, &c1.SlotRef, &c2.PlugRef, &c2.SlotRef)
+	})
+	for _, cands := range r.SlotCandidates {
+		sortCandidates(cands)
+	}
+	for _, cands := range r.PlugCandidates {
+		sortCandidates(cands)
+	}
+}
 
-	// Add declarations
-	for _, name := range snaps {
-		if seen[name] {
-			continue
-		}
-		seen[name] = true
-		ref, err := readRef(name)
-		noerror(err)
-		d := map[string]interface{}{
-			"snap-name":    ref.SnapName,
-			"snap-id":      ref.SnapID,
-			"publisher-id": ref.PublisherID,
-		}
-		if plugs, err := loadJSON(filepath.Join(name, "plugs.json")); !os.IsNotExist(err) {
-			noerror(err)
-			d["plugs"] = plugs
-		}
-		if slots, err := loadJSON(filepath.Join(name, "slots.json")); !os.IsNotExist(err) {
-			noerror(err)
-			d["slots"] = slots
-		}
-		if err := s.mockSnapDecl(ref.PublisherID, d); err != nil {
-			return fmt.Errorf("processing snap %s rules: %v", name, err)
-		}
+func (s *oneshotSimulation) simulateAutoConnect(params *autoConnectSimulation) (*autoConnectSimulationResult, error) {
+	snaps := append(params.Snaps, params.TargetSnap)
+	sc, err := s.setupScenario(&params.scenarioParams, snaps)
+	if err != nil {
+		return nil, err
 	}
+	mgr := s.mgr
 
-	targetSnap := params.TargetSnap
+	// the target can be a .snap file, use its name
+	targetSnap := sc.infos[params.TargetSnap].SnapName()
 	var targetInfo *snap.Info
 
 	var res autoConnectSimulationResult
+	res.SystemSnap = sc.flavour.systemSnap
+	res.SystemSlots = sc.systemSlots(mgr.Repository())
 	// wire-up things for candidate collection
 	res.targetSnap = targetSnap
 	ifacestate.DebugAutoConnectCheck = res.debugAutoConnectCheck
 	res.SlotCandidates = make(map[string][]candidate)
 	res.PlugCandidates = make(map[string][]candidate)
 	// Add snap metadata, and populate repo
-	for name := range seen {
-		snapYamlFn := filepath.Join(name, "snap.yaml")
-		b, err := ioutil.ReadFile(snapYamlFn)
-		noerror(err)
-		snapInfo, snapDecl, err := s.mockSnap(string(b))
-		if err != nil {
-			return fmt.Errorf("processing snap %s: %v", name, err)
-		}
-
-		inst := checkInstall(modelAs, snapInfo, snapDecl)
-		snapAppSet, err := interfaces.NewSnapAppSet(snapInfo, nil)
-		if err != nil {
-			return fmt.Errorf("processing snap %s: %v", name, err)
+	for _, name := range sc.snaps {
+		if inst := sc.refusal(name); inst != nil {
+			res.Installing = append(res.Installing, *inst)
+			continue
 		}
-
-		err = mgr.Repository().AddAppSet(snapAppSet)
+		mocked, err := s.installSnap(sc, name)
 		if err != nil {
-			return fmt.Errorf("processing snap %s: %v", snapInfo.SnapName(), err)
+			return nil, err
 		}
+		snapInfo := mocked.info
 
+		inst := sc.checkInstall(name, mocked)
 		res.Installing = append(res.Installing, inst)
 
-		if name != targetSnap {
+		if name != params.TargetSnap {
 			continue
 		}
 		targetInfo = snapInfo
@@ -518,6 +633,12 @@ func (s *oneshotSimulation) simulateAutoConnect(params *autoConnectSimulation) e
 		}
 	}
 
+	if targetInfo == nil {
+		// the target snap was refused, nothing gets connected
+		res.MissingRequired = sc.missingRequired()
+		return &res, nil
+	}
+
 	// Run the setup-snap-security task and let it finish.
 	change := s.addSetupSnapSecurityChange(&snapstate.SnapSetup{
 		SideInfo: &snap.SideInfo{
@@ -525,7 +646,7 @@ func (s *oneshotSimulation) simulateAutoConnect(params *autoConnectSimulation) e
 			Revision: snap.R(1),
 		},
 	})
-	err := s.se.Ensure()
+	err = s.se.Ensure()
 	noerror(err)
 	s.se.Wait()
 
@@ -560,10 +681,83 @@ func (s *oneshotSimulation) simulateAutoConnect(params *autoConnectSimulation) e
 		}
 	}
 
-	b, err := json.Marshal(&res)
-	noerror(err)
-	fmt.Println(string(b))
-	return nil
+	if params.Deterministic {
+		res.sort()
+	}
+	// after sorting, to get the arity analysis sorted as well
+	res.analyzeArity()
+	res.MissingRequired = sc.missingRequired()
+
+	return &res, nil
+}
+
+// analyzeArity computes the arity analysis for the plugs and slots of
+// the target snap from the candidates and the connections.
+func (r *autoConnectSimulationResult) analyzeArity() {
+	connected := make(map[interfaces.ConnRef]bool, len(r.Connections))
+	connectedPlugs := make(map[interfaces.PlugRef]bool, len(r.Connections))
+	for _, conn := range r.Connections {
+		connected[interfaces.ConnRef{PlugRef: conn.PlugRef, SlotRef: conn.SlotRef}] = true
+		connectedPlugs[conn.PlugRef] = true
+	}
+
+	for _, plug := range r.Plugs {
+		pa := plugArity{
+			Plug:      plug.Name,
+			Interface: plug.Interface,
+		}
+		seen := make(map[interfaces.SlotRef]bool)
+		slotsPerPlugAny := true
+		for _, cand := range r.SlotCandidates[plug.Name] {
+			if cand.CheckError != "" || seen[cand.SlotRef] {
+				continue
+			}
+			seen[cand.SlotRef] = true
+			pa.Candidates = append(pa.Candidates, cand.SlotRef)
+			slotsPerPlugAny = slotsPerPlugAny && cand.SlotsPerPlugAny
+		}
+		if len(pa.Candidates) != 0 {
+			pa.SlotsPerPlug = "1"
+			if slotsPerPlugAny {
+				pa.SlotsPerPlug = "*"
+			}
+		}
+		plugRef := interfaces.PlugRef{Snap: r.targetSnap, Name: plug.Name}
+		pa.Ambiguous = len(pa.Candidates) > 1 && !slotsPerPlugAny && !connectedPlugs[plugRef]
+		if pa.Ambiguous {
+			for _, slotRef := range pa.Candidates {
+				res := arityResolution{Slot: slotRef}
+				for _, other := range pa.Candidates {
+					if other != slotRef {
+						res.Disallow = append(res.Disallow, other)
+					}
+				}
+				pa.Resolutions = append(pa.Resolutions, res)
+			}
+		}
+		r.PlugArity = append(r.PlugArity, pa)
+	}
+
+	for _, slot := range r.Slots {
+		sa := slotArity{
+			Slot:         slot.Name,
+			Interface:    slot.Interface,
+			PlugsPerSlot: "*",
+		}
+		slotRef := interfaces.SlotRef{Snap: r.targetSnap, Name: slot.Name}
+		seen := make(map[interfaces.PlugRef]bool)
+		for _, cand := range r.PlugCandidates[slot.Name] {
+			if cand.CheckError != "" || seen[cand.PlugRef] {
+				continue
+			}
+			seen[cand.PlugRef] = true
+			sa.Candidates = append(sa.Candidates, cand.PlugRef)
+			if !connected[interfaces.ConnRef{PlugRef: cand.PlugRef, SlotRef: slotRef}] {
+				sa.Skipped = append(sa.Skipped, cand.PlugRef)
+			}
+		}
+		r.SlotArity = append(r.SlotArity, sa)
+	}
 }
 
 func loadJSON(fn string) (res map[string]interface{}, err error) {
@@ -577,9 +771,9 @@ func loadJSON(fn string) (res map[string]interface{}, err error) {
 	return res, nil
 }
 
-func mockDiskSnap(yamlText string, sideInfo *snap.SideInfo) (*snap.Info, error) {
+func mockDiskSnap(meta *snapMeta, sideInfo *snap.SideInfo) (*snap.Info, error) {
 	// Parse the yaml (we need the Name).
-	snapInfo, err := snap.InfoFromSnapYaml([]byte(yamlText))
+	snapInfo, err := snap.InfoFromSnapYaml(meta.snapYaml)
 	if err != nil {
 		return nil, err
 	}
@@ -587,22 +781,27 @@ func mockDiskSnap(yamlText string, sideInfo *snap.SideInfo) (*snap.Info, error)
 	// Set SideInfo so that we can use MountDir below
 	snapInfo.SideInfo = *sideInfo
 
-	// Put the YAML on disk, in the right spot.
-	metaDir := filepath.Join(snapInfo.MountDir(), "meta")
-	err = os.MkdirAll(metaDir, 0755)
-	noerror(err)
-	err = ioutil.WriteFile(filepath.Join(metaDir, "snap.yaml"), []byte(yamlText), 0644)
+	// Put the YAML, hooks etc on disk, in the right spot.
+	err = writeMetaTree(meta, snapInfo.MountDir())
 	noerror(err)
 
 	// Write the .snap to disk
 	err = os.MkdirAll(filepath.Dir(snapInfo.MountFile()), 0755)
 	noerror(err)
-	snapContents := fmt.Sprintf("%s-%s-%s", sideInfo.RealName, sideInfo.SnapID, sideInfo.Revision)
-	err = ioutil.WriteFile(snapInfo.MountFile(), []byte(snapContents), 0644)
-	noerror(err)
-	snapInfo.Size = int64(len(snapContents))
+	if meta.snapFile != "" {
+		snapFile, err := filepath.Abs(meta.snapFile)
+		noerror(err)
+		err = os.Symlink(snapFile, snapInfo.MountFile())
+		noerror(err)
+	} else {
+		snapContents := fmt.Sprintf("%s-%s-%s", sideInfo.RealName, sideInfo.SnapID, sideInfo.Revision)
+		err = ioutil.WriteFile(snapInfo.MountFile(), []byte(snapContents), 0644)
+		noerror(err)
+	}
 
-	return snapInfo, nil
+	// Read it back the way snapd does on install, this adds the
+	// implicit hooks from meta/hooks
+	return snap.ReadInfo(snapInfo.InstanceName(), sideInfo)
 }
 
 // Operations
@@ -613,20 +812,17 @@ func autoConnections(param *json.RawMessage) error {
 		return err
 	}
 
+	if err := checkOutputFormat(params.Format); err != nil {
+		return err
+	}
+
 	sim := oneshotSimulation{}
 	sim.setup(params.Classic)
-	err := sim.simulateAutoConnect(&params)
+	res, err := sim.simulateAutoConnect(&params)
 	if err != nil {
-		var errRes struct {
-			Error string `json:"error"`
-		}
-		errRes.Error = err.Error()
-		b, err := json.Marshal(&errRes)
-		noerror(err)
-		fmt.Println(string(b))
-		return nil
+		return outputError(params.Format, err)
 	}
 	sim.finish()
 
-	return nil
+	return outputResult(params.Format, res)
 }
@@ -0,0 +1,324 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// newTestSimulation sets up a simulation whose root directory is removed
+// at the end of the test.
+func newTestSimulation(t *testing.T, classic bool) *oneshotSimulation {
+	t.Helper()
+	sim := &oneshotSimulation{}
+	sim.setup(classic)
+	rootDir := dirs.GlobalRootDir
+	t.Cleanup(func() {
+		sim.finish()
+		os.RemoveAll(rootDir)
+		dirs.SetRootDir("")
+	})
+	return sim
+}
+
+// writeSnapDir writes a snap directory with the snap.yaml and, if not
+// empty, the plugs.json and slots.json declaration rules. The snap is
+// named after the directory.
+func writeSnapDir(t *testing.T, name, publisherID, snapYaml, plugs, slots string) {
+	t.Helper()
+	writeTestFile(t, filepath.Join(name, "snap.yaml"), snapYaml)
+	snapName := filepath.Base(name)
+	ref := &snapRef{SnapName: snapName, SnapID: snapName + "-id", PublisherID: publisherID}
+	if err := writeJSON(name, ".snap.json", ref); err != nil {
+		t.Fatal(err)
+	}
+	if plugs != "" {
+		writeTestFile(t, filepath.Join(name, "plugs.json"), plugs)
+	}
+	if slots != "" {
+		writeTestFile(t, filepath.Join(name, "slots.json"), slots)
+	}
+}
+
+// content plug and slot snaps, content auto-connects only between snaps
+// of the same publisher in the base-declaration
+const (
+	contentPlugYaml = `name: %s
+version: 1
+plugs:
+  data:
+    interface: content
+    content: data
+    target: $SNAP/data
+`
+	contentSlotYaml = `name: %s
+version: 1
+slots:
+  data:
+    interface: content
+    content: data
+    read: [$SNAP/data]
+`
+)
+
+// slotCandidate is a candidate slot for a plug of the target snap, only
+// the fields used by the arity analysis are set.
+func slotCandidate(snapName, slotName string, slotsPerPlugAny bool, checkErr string) candidate {
+	return candidate{
+		SlotRef:         interfaces.SlotRef{Snap: snapName, Name: slotName},
+		SlotsPerPlugAny: slotsPerPlugAny,
+		CheckError:      checkErr,
+	}
+}
+
+// plugCandidate is a candidate plug for a slot of the target snap.
+func plugCandidate(snapName, plugName, checkErr string) candidate {
+	return candidate{
+		PlugRef:    interfaces.PlugRef{Snap: snapName, Name: plugName},
+		CheckError: checkErr,
+	}
+}
+
+func TestAnalyzeArity(t *testing.T) {
+	r := &autoConnectSimulationResult{
+		targetSnap: "foo",
+		Plugs: []side{
+			{Interface: "x11", Name: "x11"},
+			{Interface: "network", Name: "network"},
+			{Interface: "content", Name: "content"},
+			{Interface: "camera", Name: "camera"},
+		},
+		Slots: []side{
+			{Interface: "content", Name: "data"},
+		},
+		SlotCandidates: map[string][]candidate{
+			"x11": {
+				slotCandidate("bar", "x11", false, ""),
+				slotCandidate("baz", "x11", false, ""),
+				// checked once per attempt
+				slotCandidate("bar", "x11", false, ""),
+				slotCandidate("qux", "x11", false, "auto-connection not allowed"),
+			},
+			"network": {
+				slotCandidate("bar", "network", true, ""),
+				slotCandidate("baz", "network", true, ""),
+			},
+			"content": {
+				slotCandidate("bar", "content", false, ""),
+				slotCandidate("baz", "content", false, ""),
+			},
+		},
+		PlugCandidates: map[string][]candidate{
+			"data": {
+				plugCandidate("bar", "data", ""),
+				plugCandidate("baz", "data", ""),
+				plugCandidate("qux", "data", "auto-connection not allowed"),
+			},
+		},
+		Connections: []connection{
+			{
+				Interface: "network",
+				PlugRef:   interfaces.PlugRef{Snap: "foo", Name: "network"},
+				SlotRef:   interfaces.SlotRef{Snap: "bar", Name: "network"},
+			},
+			{
+				Interface: "network",
+				PlugRef:   interfaces.PlugRef{Snap: "foo", Name: "network"},
+				SlotRef:   interfaces.SlotRef{Snap: "baz", Name: "network"},
+			},
+			// e.g. from a connect hook or a previous connection
+			{
+				Interface: "content",
+				PlugRef:   interfaces.PlugRef{Snap: "foo", Name: "content"},
+				SlotRef:   interfaces.SlotRef{Snap: "bar", Name: "content"},
+			},
+			{
+				Interface: "content",
+				PlugRef:   interfaces.PlugRef{Snap: "bar", Name: "data"},
+				SlotRef:   interfaces.SlotRef{Snap: "foo", Name: "data"},
+			},
+		},
+	}
+	r.analyzeArity()
+
+	barX11 := interfaces.SlotRef{Snap: "bar", Name: "x11"}
+	bazX11 := interfaces.SlotRef{Snap: "baz", Name: "x11"}
+	wantPlugs := []plugArity{
+		{
+			Plug:         "x11",
+			Interface:    "x11",
+			SlotsPerPlug: "1",
+			Candidates:   []interfaces.SlotRef{barX11, bazX11},
+			Ambiguous:    true,
+			Resolutions: []arityResolution{
+				{Slot: barX11, Disallow: []interfaces.SlotRef{bazX11}},
+				{Slot: bazX11, Disallow: []interfaces.SlotRef{barX11}},
+			},
+		},
+		{
+			Plug:         "network",
+			Interface:    "network",
+			SlotsPerPlug: "*",
+			Candidates: []interfaces.SlotRef{
+				{Snap: "bar", Name: "network"},
+				{Snap: "baz", Name: "network"},
+			},
+		},
+		{
+			// connected, so not skipped
+			Plug:         "content",
+			Interface:    "content",
+			SlotsPerPlug: "1",
+			Candidates: []interfaces.SlotRef{
+				{Snap: "bar", Name: "content"},
+				{Snap: "baz", Name: "content"},
+			},
+		},
+		{
+			Plug:      "camera",
+			Interface: "camera",
+		},
+	}
+	if !reflect.DeepEqual(r.PlugArity, wantPlugs) {
+		t.Errorf("expected plug arity %+v, got %+v", wantPlugs, r.PlugArity)
+	}
+
+	wantSlots := []slotArity{
+		{
+			Slot:         "data",
+			Interface:    "content",
+			PlugsPerSlot: "*",
+			Candidates: []interfaces.PlugRef{
+				{Snap: "bar", Name: "data"},
+				{Snap: "baz", Name: "data"},
+			},
+			Skipped: []interfaces.PlugRef{
+				{Snap: "baz", Name: "data"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(r.SlotArity, wantSlots) {
+		t.Errorf("expected slot arity %+v, got %+v", wantSlots, r.SlotArity)
+	}
+}
+
+func TestPinTimestamps(t *testing.T) {
+	am := &assertsMock{}
+	am.setupAsserts(state.New(nil))
+	am.pinTimestamps()
+
+	decl, err := am.signSnapDecl("pub", map[string]interface{}{
+		"snap-name":    "foo",
+		"snap-id":      "foo-id",
+		"publisher-id": "pub",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decl.Timestamp().Equal(pinnedTimestamp) {
+		t.Errorf("expected timestamp %v, got %v", pinnedTimestamp, decl.Timestamp())
+	}
+	if decl.SignKeyID() != am.storeSigning.KeyID {
+		t.Errorf("expected to be signed with the pinned key")
+	}
+	// the declaration predates the mock store keys but not the
+	// pinned one
+	if err := am.db.Add(decl); err != nil {
+		t.Fatal(err)
+	}
+	a, err := am.db.Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  "16",
+		"snap-id": "foo-id",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.(*asserts.SnapDeclaration).SnapName() != "foo" {
+		t.Errorf("unexpected snap-declaration %v", a.Headers())
+	}
+}
+
+func TestSimulateAutoConnectDeterministic(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeSnapDir(t, "consumer", "pub1", fmt.Sprintf(contentPlugYaml, "consumer")+`  x11:
+  home:
+  network:
+slots:
+  shared:
+    interface: content
+    content: shared
+    read: [$SNAP/shared]
+`, "", "")
+	writeSnapDir(t, "provider1", "pub1", fmt.Sprintf(contentSlotYaml, "provider1"), "", "")
+	writeSnapDir(t, "provider2", "pub1", fmt.Sprintf(contentSlotYaml, "provider2"), "", "")
+	writeSnapDir(t, "user1", "pub1", `name: user1
+version: 1
+plugs:
+  shared:
+    interface: content
+    content: shared
+    target: $SNAP/shared
+`, "", "")
+	writeSnapDir(t, "user2", "pub2", `name: user2
+version: 1
+plugs:
+  shared:
+    interface: content
+    content: shared
+    target: $SNAP/shared
+`, "", "")
+
+	simulate := func() []byte {
+		sim := newTestSimulation(t, false)
+		params := &autoConnectSimulation{
+			TargetSnap: "consumer",
+			Snaps:      []string{"user2", "provider2", "user1", "provider1"},
+		}
+		params.Deterministic = true
+		res, err := sim.simulateAutoConnect(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.SlotCandidates["data"]) < 2 || len(res.PlugCandidates["shared"]) < 2 {
+			t.Fatalf("expected several candidates for data and shared, got %+v and %+v", res.SlotCandidates, res.PlugCandidates)
+		}
+		b, err := json.Marshal(res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	out1 := simulate()
+	out2 := simulate()
+	if !bytes.Equal(out1, out2) {
+		t.Errorf("outputs differ:\n%s\n%s", out1, out2)
+	}
+}
@@ -0,0 +1,353 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/snap"
+)
+
+type suggestSimulation struct {
+	scenarioParams
+
+	// Plug is <snap>:<plug>
+	Plug string `json:"plug"`
+	// Slot is <snap>:<slot>
+	Slot string `json:"slot"`
+	// Outcome is the desired outcome: auto (the default) or connect
+	Outcome string `json:"outcome"`
+
+	Snaps []string `json:"snaps"`
+}
+
+// suggestion is a candidate change to the declaration rules of a snap.
+type suggestion struct {
+	// Snap is the snap whose declaration would change
+	Snap string `json:"snap"`
+	// Side is plugs or slots, the file is <snap>/<side>.json
+	Side      string `json:"side"`
+	Interface string `json:"interface"`
+
+	// Constraints is the added alternative
+	Constraints interface{} `json:"constraints"`
+	// Rule is the resulting rule for the interface
+	Rule map[string]interface{} `json:"rule"`
+
+	// Narrowness ranks how narrowly scoped the suggestion is,
+	// higher is narrower
+	Narrowness int `json:"narrowness"`
+
+	Verified bool `json:"verified"`
+	// Error is the verification error if not verified
+	Error string `json:"error,omitempty"`
+}
+
+type suggestResult struct {
+	Current *pairVerdict `json:"current"`
+
+	// Suggestions are ranked, verified ones first
+	Suggestions []*suggestion `json:"suggestions"`
+}
+
+// constraintsCandidates returns the candidate constraints for a rule on
+// one side given the other side snap declaration, from the narrowest to
+// the broadest, with their narrowness.
+func constraintsCandidates(side string, label map[string]interface{}, otherDecl *asserts.SnapDeclaration, store string) (cands []interface{}, narrowness []int) {
+	own, other := "plug", "slot"
+	if side == "slots" {
+		own, other = "slot", "plug"
+	}
+	labelN := 0
+	if len(label) != 0 {
+		labelN = 1
+	}
+	storeN := 0
+	if store != "" {
+		storeN = 1
+	}
+	add := func(c interface{}, n int) {
+		for _, prev := range cands {
+			if reflect.DeepEqual(prev, c) {
+				return
+			}
+		}
+		cands = append(cands, c)
+		narrowness = append(narrowness, n)
+	}
+	withLabel := func(c map[string]interface{}) map[string]interface{} {
+		if len(label) != 0 {
+			c[own+"-attributes"] = label
+		}
+		return c
+	}
+	withStore := func(c map[string]interface{}) map[string]interface{} {
+		if store != "" {
+			c["on-store"] = []interface{}{store}
+		}
+		return c
+	}
+	if otherDecl != nil {
+		snapID := func() map[string]interface{} {
+			return map[string]interface{}{
+				other + "-snap-id": []interface{}{otherDecl.SnapID()},
+			}
+		}
+		publisherID := func() map[string]interface{} {
+			return map[string]interface{}{
+				other + "-publisher-id": []interface{}{otherDecl.PublisherID()},
+			}
+		}
+		add(withStore(withLabel(snapID())), 4+labelN+storeN)
+		add(withLabel(snapID()), 4+labelN)
+		add(snapID(), 4)
+		add(withLabel(publisherID()), 2+labelN)
+		add(publisherID(), 2)
+	}
+	if labelN+storeN != 0 {
+		add(withStore(withLabel(map[string]interface{}{})), labelN+storeN)
+	}
+	add("true", 0)
+	return cands, narrowness
+}
+
+// labelAttr returns the attribute named as the interface, if it is a
+// string, as for the content interface label.
+func labelAttr(iface string, attrs map[string]interface{}) map[string]interface{} {
+	if v, ok := attrs[iface].(string); ok && v != "" {
+		return map[string]interface{}{iface: v}
+	}
+	return nil
+}
+
+// withAlternative returns a copy of the interface rule with the
+// constraints added as an alternative for the given allow-* key.
+func withAlternative(rule interface{}, key string, constraints interface{}) map[string]interface{} {
+	res := make(map[string]interface{})
+	if m, ok := rule.(map[string]interface{}); ok {
+		for k, v := range m {
+			res[k] = v
+		}
+	}
+	switch prev := res[key].(type) {
+	case map[string]interface{}:
+		if constraints == "true" {
+			res[key] = constraints
+		} else {
+			res[key] = []interface{}{prev, constraints}
+		}
+	case []interface{}:
+		if constraints == "true" {
+			res[key] = constraints
+		} else {
+			alts := make([]interface{}, len(prev), len(prev)+1)
+			copy(alts, prev)
+			res[key] = append(alts, constraints)
+		}
+	case string:
+		// "true" already allows everything
+		if prev != "true" {
+			res[key] = constraints
+		}
+	default:
+		res[key] = constraints
+	}
+	return res
+}
+
+func (s *oneshotSimulation) simulateSuggest(params *suggestSimulation) (*suggestResult, error) {
+	key := "allow-auto-connection"
+	switch params.Outcome {
+	case "", "auto":
+	case "connect":
+		key = "allow-connection"
+	default:
+		return nil, fmt.Errorf("unsupported outcome: %s", params.Outcome)
+	}
+	plugSnap, plugName, err := splitSnapRef(params.Plug, "plug")
+	if err != nil {
+		return nil, err
+	}
+	slotSnap, slotName, err := splitSnapRef(params.Slot, "slot")
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.setupCorpus(&params.scenarioParams, params.Snaps)
+	if err != nil {
+		return nil, err
+	}
+	repo := s.mgr.Repository()
+	// the snaps can be given as snap directories or .snap files
+	if info := c.sc.infos[plugSnap]; info != nil {
+		plugSnap = info.SnapName()
+	}
+	if info := c.sc.infos[slotSnap]; info != nil {
+		slotSnap = info.SnapName()
+	}
+	plug := repo.Plug(plugSnap, plugName)
+	if plug == nil {
+		return nil, fmt.Errorf("cannot find plug %s", params.Plug)
+	}
+	slot := repo.Slot(slotSnap, slotName)
+	if slot == nil {
+		return nil, fmt.Errorf("cannot find slot %s", params.Slot)
+	}
+	if plug.Interface != slot.Interface {
+		return nil, fmt.Errorf("plug %s and slot %s are for different interfaces", params.Plug, params.Slot)
+	}
+	iface := plug.Interface
+	// all the slots the plug could be auto-connected to, for ambiguity
+	ifaceSlots := repo.AllSlots(iface)
+
+	achieved := func(v *pairVerdict) bool {
+		if key == "allow-connection" {
+			return v.AllowConnection
+		}
+		// not ambiguous with other slots
+		return v.Verdict == verdictAuto
+	}
+
+	current, err := c.evaluatePairWith(plug, slot, ifaceSlots, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	res := &suggestResult{Current: current}
+	if achieved(current) {
+		return res, nil
+	}
+
+	type sideInfo struct {
+		side      string
+		snapName  string
+		label     map[string]interface{}
+		otherDecl *asserts.SnapDeclaration
+	}
+	sides := []sideInfo{
+		{"plugs", plugSnap, labelAttr(iface, plug.Attrs), c.decls[slotSnap]},
+		{"slots", slotSnap, labelAttr(iface, slot.Attrs), c.decls[plugSnap]},
+	}
+	for _, si := range sides {
+		dir := c.dirs[si.snapName]
+		if dir == "" || c.decls[si.snapName] == nil {
+			// not a snap with a declaration we can change
+			continue
+		}
+		ref, headers := readDeclHeaders(dir)
+		var rules map[string]interface{}
+		if m, ok := headers[si.side].(map[string]interface{}); ok {
+			rules = m
+		}
+		cands, narrowness := constraintsCandidates(si.side, si.label, si.otherDecl, params.Store)
+		for i, constraints := range cands {
+			rule := withAlternative(rules[iface], key, constraints)
+			sugg := &suggestion{
+				Snap:        si.snapName,
+				Side:        si.side,
+				Interface:   iface,
+				Constraints: constraints,
+				Rule:        rule,
+				Narrowness:  narrowness[i],
+			}
+			res.Suggestions = append(res.Suggestions, sugg)
+
+			newRules := make(map[string]interface{}, len(rules)+1)
+			for k, v := range rules {
+				newRules[k] = v
+			}
+			newRules[iface] = rule
+			newHeaders := make(map[string]interface{}, len(headers))
+			for k, v := range headers {
+				newHeaders[k] = v
+			}
+			newHeaders[si.side] = newRules
+			decl, err := s.signSnapDecl(ref.PublisherID, newHeaders)
+			if err != nil {
+				sugg.Error = err.Error()
+				continue
+			}
+			v, err := c.evaluatePairWith(plug, slot, ifaceSlots, si.snapName, decl)
+			if err != nil {
+				return nil, err
+			}
+			sugg.Verified = achieved(v)
+			if !sugg.Verified {
+				sugg.Error = v.Reason
+			}
+		}
+	}
+	sort.SliceStable(res.Suggestions, func(i, j int) bool {
+		s1 := res.Suggestions[i]
+		s2 := res.Suggestions[j]
+		if s1.Verified != s2.Verified {
+			return s1.Verified
+		}
+		return s1.Narrowness > s2.Narrowness
+	})
+	return res, nil
+}
+
+// evaluatePairWith evaluates a plug/slot pair using the given declaration
+// for one of the snaps instead of the one of the corpus, if any. As when
+// auto-connecting, an auto verdict holds only if the plug is not allowed
+// to auto-connect also to other of the given slots, see checkAmbiguity.
+func (c *corpus) evaluatePairWith(plug *snap.PlugInfo, slot *snap.SlotInfo, slots []*snap.SlotInfo, snapName string, decl *asserts.SnapDeclaration) (*pairVerdict, error) {
+	if decl != nil {
+		orig := c.decls[snapName]
+		c.decls[snapName] = decl
+		defer func() {
+			c.decls[snapName] = orig
+		}()
+	}
+	v, err := c.evaluatePair(plug, slot)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkAmbiguity(v, plug, slots); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Operations
+
+func suggest(param *json.RawMessage) error {
+	var params suggestSimulation
+	if err := json.Unmarshal([]byte(*param), &params); err != nil {
+		return err
+	}
+
+	sim := oneshotSimulation{}
+	sim.setup(params.Classic)
+	res, err := sim.simulateSuggest(&params)
+	if err != nil {
+		return outputError("json", err)
+	}
+	sim.finish()
+
+	b, err := json.Marshal(res)
+	noerror(err)
+	fmt.Println(string(b))
+	return nil
+}
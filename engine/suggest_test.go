@@ -0,0 +1,153 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+)
+
+func TestConstraintsCandidates(t *testing.T) {
+	storeSigning := assertstest.NewStoreStack("canonical", nil)
+	a, err := storeSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      "bar-id",
+		"snap-name":    "bar",
+		"publisher-id": "bar-pub",
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	barDecl := a.(*asserts.SnapDeclaration)
+	label := map[string]interface{}{"content": "data"}
+
+	type cand struct {
+		constraints interface{}
+		narrowness  int
+	}
+	m := func(kv ...interface{}) map[string]interface{} {
+		res := make(map[string]interface{})
+		for i := 0; i < len(kv); i += 2 {
+			res[kv[i].(string)] = kv[i+1]
+		}
+		return res
+	}
+	snapIDs := []interface{}{"bar-id"}
+	publisherIDs := []interface{}{"bar-pub"}
+	onStore := []interface{}{"my-store"}
+	for _, tc := range []struct {
+		comment   string
+		side      string
+		label     map[string]interface{}
+		otherDecl *asserts.SnapDeclaration
+		store     string
+		cands     []cand
+	}{
+		{"no other declaration", "plugs", nil, nil, "", []cand{
+			{"true", 0},
+		}},
+		{"no label nor store", "plugs", nil, barDecl, "", []cand{
+			{m("slot-snap-id", snapIDs), 4},
+			{m("slot-publisher-id", publisherIDs), 2},
+			{"true", 0},
+		}},
+		{"label", "plugs", label, barDecl, "", []cand{
+			{m("slot-snap-id", snapIDs, "plug-attributes", label), 5},
+			{m("slot-snap-id", snapIDs), 4},
+			{m("slot-publisher-id", publisherIDs, "plug-attributes", label), 3},
+			{m("slot-publisher-id", publisherIDs), 2},
+			{m("plug-attributes", label), 1},
+			{"true", 0},
+		}},
+		{"store", "plugs", nil, barDecl, "my-store", []cand{
+			{m("slot-snap-id", snapIDs, "on-store", onStore), 5},
+			{m("slot-snap-id", snapIDs), 4},
+			{m("slot-publisher-id", publisherIDs), 2},
+			{m("on-store", onStore), 1},
+			{"true", 0},
+		}},
+		{"label and store on the slot side", "slots", label, barDecl, "my-store", []cand{
+			{m("plug-snap-id", snapIDs, "slot-attributes", label, "on-store", onStore), 6},
+			{m("plug-snap-id", snapIDs, "slot-attributes", label), 5},
+			{m("plug-snap-id", snapIDs), 4},
+			{m("plug-publisher-id", publisherIDs, "slot-attributes", label), 3},
+			{m("plug-publisher-id", publisherIDs), 2},
+			{m("slot-attributes", label, "on-store", onStore), 2},
+			{"true", 0},
+		}},
+	} {
+		cands, narrowness := constraintsCandidates(tc.side, tc.label, tc.otherDecl, tc.store)
+		var got []cand
+		for i := range cands {
+			got = append(got, cand{cands[i], narrowness[i]})
+		}
+		if !reflect.DeepEqual(got, tc.cands) {
+			t.Errorf("%s: expected %v, got %v", tc.comment, tc.cands, got)
+		}
+	}
+}
+
+func TestWithAlternative(t *testing.T) {
+	const key = "allow-auto-connection"
+	snapID := map[string]interface{}{"slot-snap-id": []interface{}{"bar-id"}}
+	publisherID := map[string]interface{}{"slot-publisher-id": []interface{}{"bar-pub"}}
+	other := map[string]interface{}{"allow-installation": "true"}
+	for _, tc := range []struct {
+		comment     string
+		rule        interface{}
+		constraints interface{}
+		want        map[string]interface{}
+	}{
+		{"no rule", nil, snapID,
+			map[string]interface{}{key: snapID}},
+		{"rule without the key", other, snapID,
+			map[string]interface{}{"allow-installation": "true", key: snapID}},
+		{"false", map[string]interface{}{key: "false"}, snapID,
+			map[string]interface{}{key: snapID}},
+		{"true already allows everything", map[string]interface{}{key: "true"}, snapID,
+			map[string]interface{}{key: "true"}},
+		{"map", map[string]interface{}{key: publisherID}, snapID,
+			map[string]interface{}{key: []interface{}{publisherID, snapID}}},
+		{"map and true", map[string]interface{}{key: publisherID}, "true",
+			map[string]interface{}{key: "true"}},
+		{"list", map[string]interface{}{key: []interface{}{publisherID}}, snapID,
+			map[string]interface{}{key: []interface{}{publisherID, snapID}}},
+		{"list and true", map[string]interface{}{key: []interface{}{publisherID}}, "true",
+			map[string]interface{}{key: "true"}},
+	} {
+		got := withAlternative(tc.rule, key, tc.constraints)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.comment, tc.want, got)
+		}
+	}
+
+	// the rule is not modified
+	alts := []interface{}{publisherID}
+	rule := map[string]interface{}{key: alts}
+	withAlternative(rule, key, snapID)
+	if len(alts) != 1 || len(rule) != 1 || !reflect.DeepEqual(rule[key], []interface{}{publisherID}) {
+		t.Errorf("the rule was modified: %v", rule)
+	}
+}
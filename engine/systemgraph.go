@@ -0,0 +1,274 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+)
+
+const settleTimeout = 30 * time.Second
+
+type systemGraphSimulation struct {
+	scenarioParams
+
+	Snaps []string `json:"snaps"`
+}
+
+type graphSnap struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Plugs []side `json:"plugs"`
+	Slots []side `json:"slots"`
+}
+
+type graphConnection struct {
+	Interface string             `json:"interface"`
+	PlugRef   interfaces.PlugRef `json:"plug"`
+	SlotRef   interfaces.SlotRef `json:"slot"`
+	Auto      bool               `json:"auto"`
+	ByGadget  bool               `json:"by-gadget,omitempty"`
+}
+
+type systemGraphResult struct {
+	// Installing is in installation order
+	Installing []installation `json:"installing"`
+	// MissingRequired are the snaps required by the validation sets
+	// that are missing
+	MissingRequired []missingSnap `json:"missing-required,omitempty"`
+
+	SystemSnap string `json:"system-snap"`
+
+	// Snaps include the system snaps
+	Snaps []graphSnap `json:"snaps"`
+
+	Connections []graphConnection `json:"connections"`
+}
+
+// installRank ranks snap types by when they get installed when seeding.
+var installRank = map[snap.Type]int{
+	snap.TypeSnapd:  0,
+	snap.TypeOS:     1,
+	snap.TypeBase:   2,
+	snap.TypeKernel: 3,
+	snap.TypeGadget: 4,
+	snap.TypeApp:    5,
+}
+
+// contentDefaultProviders returns the names of the default-providers
+// of the content plugs of the snap.
+func contentDefaultProviders(info *snap.Info) []string {
+	var providers []string
+	for _, plug := range info.Plugs {
+		if plug.Interface != "content" {
+			continue
+		}
+		var dprovider string
+		if err := plug.Attr("default-provider", &dprovider); err != nil || dprovider == "" {
+			continue
+		}
+		// the default-provider can be <snap>:<slot>
+		providers = append(providers, strings.SplitN(dprovider, ":", 2)[0])
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// installOrder returns the snaps in a realistic installation order:
+// first by type as when seeding and then with content default-providers
+// before the snaps using them, otherwise keeping the given order.
+func installOrder(snaps []string, infos map[string]*snap.Info) []string {
+	byType := make([]string, len(snaps))
+	copy(byType, snaps)
+	sort.SliceStable(byType, func(i, j int) bool {
+		return installRank[infos[byType[i]].Type()] < installRank[infos[byType[j]].Type()]
+	})
+
+	byName := make(map[string]string, len(snaps))
+	for _, name := range snaps {
+		byName[infos[name].SnapName()] = name
+	}
+
+	order := make([]string, 0, len(snaps))
+	visited := make(map[string]bool, len(snaps))
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		for _, provider := range contentDefaultProviders(infos[name]) {
+			if dep, ok := byName[provider]; ok {
+				visit(dep)
+			}
+		}
+		order = append(order, name)
+	}
+	for _, name := range byType {
+		visit(name)
+	}
+	return order
+}
+
+func (s *oneshotSimulation) autoConnect(snapName string) error {
+	change := s.addSetupSnapSecurityChange(&snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: snapName,
+			Revision: snap.R(1),
+		},
+	})
+	if err := s.o.Settle(settleTimeout); err != nil {
+		return err
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	if err := change.Err(); err != nil {
+		return fmt.Errorf("auto-connecting snap %s: %v", snapName, err)
+	}
+	return nil
+}
+
+func (s *oneshotSimulation) simulateSystemGraph(params *systemGraphSimulation) (*systemGraphResult, error) {
+	sc, err := s.setupScenario(&params.scenarioParams, params.Snaps)
+	if err != nil {
+		return nil, err
+	}
+	repo := s.mgr.Repository()
+
+	var res systemGraphResult
+	res.SystemSnap = sc.flavour.systemSnap
+
+	// the system snaps were installed with the manager,
+	// auto-connect them first
+	for _, yamlText := range sc.flavour.yamls {
+		info, err := snap.InfoFromSnapYaml([]byte(yamlText))
+		noerror(err)
+		if sc.infos[info.SnapName()] != nil {
+			// part of the scenario, see below
+			continue
+		}
+		if err := s.autoConnect(info.SnapName()); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range installOrder(sc.snaps, sc.infos) {
+		if inst := sc.refusal(name); inst != nil {
+			res.Installing = append(res.Installing, *inst)
+			continue
+		}
+		mocked, err := s.installSnap(sc, name)
+		if err != nil {
+			return nil, err
+		}
+		res.Installing = append(res.Installing, sc.checkInstall(name, mocked))
+		if err := s.autoConnect(mocked.info.SnapName()); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, mocked := range sc.installed {
+		info := mocked.info
+		gsnap := graphSnap{
+			Name: info.SnapName(),
+			Type: string(info.Type()),
+		}
+		for _, plug := range repo.Plugs(info.SnapName()) {
+			gsnap.Plugs = append(gsnap.Plugs, side{
+				Interface: plug.Interface,
+				Name:      plug.Name,
+			})
+		}
+		for _, slot := range repo.Slots(info.SnapName()) {
+			gsnap.Slots = append(gsnap.Slots, side{
+				Interface: slot.Interface,
+				Name:      slot.Name,
+			})
+		}
+		res.Snaps = append(res.Snaps, gsnap)
+	}
+	sort.Slice(res.Snaps, func(i, j int) bool {
+		return res.Snaps[i].Name < res.Snaps[j].Name
+	})
+
+	s.state.Lock()
+	conns, err := ifacestate.ConnectionStates(s.state)
+	s.state.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	for id, connState := range conns {
+		if connState.Undesired {
+			continue
+		}
+		connRef, err := interfaces.ParseConnRef(id)
+		if err != nil {
+			return nil, err
+		}
+		res.Connections = append(res.Connections, graphConnection{
+			Interface: connState.Interface,
+			PlugRef:   connRef.PlugRef,
+			SlotRef:   connRef.SlotRef,
+			Auto:      connState.Auto,
+			ByGadget:  connState.ByGadget,
+		})
+	}
+	// map iteration order is random, always sort
+	sort.Slice(res.Connections, func(i, j int) bool {
+		c1 := &res.Connections[i]
+		c2 := &res.Connections[j]
+		return connRefLess(&c1.PlugRef, &c1.SlotRef, &c2.PlugRef, &c2.SlotRef)
+	})
+	res.MissingRequired = sc.missingRequired()
+
+	return &res, nil
+}
+
+// Operations
+
+func systemGraph(param *json.RawMessage) error {
+	var params systemGraphSimulation
+	if err := json.Unmarshal([]byte(*param), &params); err != nil {
+		return err
+	}
+
+	if err := checkOutputFormat(params.Format); err != nil {
+		return err
+	}
+
+	sim := oneshotSimulation{}
+	sim.setup(params.Classic)
+	res, err := sim.simulateSystemGraph(&params)
+	if err != nil {
+		return outputError(params.Format, err)
+	}
+	sim.finish()
+
+	return outputResult(params.Format, res)
+}
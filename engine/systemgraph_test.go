@@ -0,0 +1,133 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/snapcore/snapd/snap"
+)
+
+// graphInfos builds snap infos from snap.yaml texts, keyed by the
+// given snap paths.
+func graphInfos(t *testing.T, yamls map[string]string) map[string]*snap.Info {
+	t.Helper()
+	infos := make(map[string]*snap.Info, len(yamls))
+	for name, yamlText := range yamls {
+		info, err := snap.InfoFromSnapYaml([]byte(yamlText))
+		if err != nil {
+			t.Fatal(err)
+		}
+		infos[name] = info
+	}
+	return infos
+}
+
+// contentConsumerYaml is a snap with a content plug for each of the
+// given default-providers.
+func contentConsumerYaml(name string, providers ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "name: %s\nversion: 1\nplugs:\n", name)
+	for i, provider := range providers {
+		fmt.Fprintf(&b, "  data%d:\n    interface: content\n    content: data%d\n    target: $SNAP/data%d\n    default-provider: %s\n", i, i, i, provider)
+	}
+	return b.String()
+}
+
+func TestContentDefaultProviders(t *testing.T) {
+	infos := graphInfos(t, map[string]string{
+		"foo": contentConsumerYaml("foo", "zzz", "bar:data", "baz") + `  x11:
+  other:
+    interface: content
+    content: other
+    target: $SNAP/other
+`,
+	})
+	want := []string{"bar", "baz", "zzz"}
+	if got := contentDefaultProviders(infos["foo"]); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestInstallOrder(t *testing.T) {
+	for _, tc := range []struct {
+		comment string
+		yamls   map[string]string
+		snaps   []string
+		order   []string
+	}{{
+		comment: "type ranking",
+		yamls: map[string]string{
+			"app":       "name: app\nversion: 1\n",
+			"pc":        "name: pc\nversion: 1\ntype: gadget\n",
+			"pc-kernel": "name: pc-kernel\nversion: 1\ntype: kernel\n",
+			"core22":    "name: core22\nversion: 1\ntype: base\n",
+			"core":      "name: core\nversion: 1\ntype: os\n",
+			"snapd":     "name: snapd\nversion: 1\ntype: snapd\n",
+		},
+		snaps: []string{"app", "pc", "pc-kernel", "core22", "core", "snapd"},
+		order: []string{"snapd", "core", "core22", "pc-kernel", "pc", "app"},
+	}, {
+		comment: "stable otherwise",
+		yamls: map[string]string{
+			"b":      "name: b\nversion: 1\n",
+			"a":      "name: a\nversion: 1\n",
+			"core22": "name: core22\nversion: 1\ntype: base\n",
+			"c":      "name: c\nversion: 1\n",
+		},
+		snaps: []string{"b", "a", "core22", "c"},
+		order: []string{"core22", "b", "a", "c"},
+	}, {
+		comment: "provider listed after its consumer",
+		yamls: map[string]string{
+			"consumer":     contentConsumerYaml("consumer", "provider:data", "elsewhere"),
+			"other":        "name: other\nversion: 1\n",
+			"dir/provider": "name: provider\nversion: 1\n",
+		},
+		snaps: []string{"consumer", "other", "dir/provider"},
+		order: []string{"dir/provider", "consumer", "other"},
+	}, {
+		comment: "transitive providers",
+		yamls: map[string]string{
+			"a": contentConsumerYaml("a", "b"),
+			"b": contentConsumerYaml("b", "c"),
+			"c": "name: c\nversion: 1\n",
+		},
+		snaps: []string{"a", "b", "c"},
+		order: []string{"c", "b", "a"},
+	}, {
+		comment: "provider cycle",
+		yamls: map[string]string{
+			"a": contentConsumerYaml("a", "b"),
+			"b": contentConsumerYaml("b", "a"),
+			"c": "name: c\nversion: 1\n",
+		},
+		snaps: []string{"c", "a", "b"},
+		order: []string{"c", "b", "a"},
+	}} {
+		infos := graphInfos(t, tc.yamls)
+		if got := installOrder(tc.snaps, infos); !reflect.DeepEqual(got, tc.order) {
+			t.Errorf("%s: expected %v, got %v", tc.comment, tc.order, got)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/snap"
+)
+
+// readValidationSets reads the validation-set assertions from the given
+// files, each can hold more than one.
+func readValidationSets(fns []string) ([]*asserts.ValidationSet, error) {
+	var sets []*asserts.ValidationSet
+	for _, fn := range fns {
+		f, err := os.Open(fn)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		dec := asserts.NewDecoder(f)
+		for {
+			a, err := dec.Decode()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				f.Close()
+				return nil, fmt.Errorf("cannot decode %s: %v", fn, err)
+			}
+			if vs, ok := a.(*asserts.ValidationSet); ok {
+				sets = append(sets, vs)
+				found = true
+			}
+		}
+		f.Close()
+		if !found {
+			return nil, fmt.Errorf("no validation-set assertion in %s", fn)
+		}
+	}
+	return sets, nil
+}
+
+// validationSetKey identifies a validation set in reports, as
+// <account-id>/<name>=<sequence>.
+func validationSetKey(vs *asserts.ValidationSet) string {
+	return fmt.Sprintf("%s/%s=%d", vs.AccountID(), vs.Name(), vs.Sequence())
+}
+
+// pinnedRevisions returns the revisions the validation sets pin by snap
+// name, it fails on conflicting ones.
+func pinnedRevisions(sets []*asserts.ValidationSet) (map[string]int, error) {
+	pinned := make(map[string]int)
+	pinnedBy := make(map[string]string)
+	for _, vs := range sets {
+		for _, vsnap := range vs.Snaps() {
+			if vsnap.Presence == asserts.PresenceInvalid || vsnap.Revision <= 0 {
+				continue
+			}
+			if rev, ok := pinned[vsnap.Name]; ok && rev != vsnap.Revision {
+				return nil, fmt.Errorf("validation sets %s and %s pin %s to different revisions %d and %d", pinnedBy[vsnap.Name], validationSetKey(vs), vsnap.Name, rev, vsnap.Revision)
+			}
+			pinned[vsnap.Name] = vsnap.Revision
+			pinnedBy[vsnap.Name] = validationSetKey(vs)
+		}
+	}
+	return pinned, nil
+}
+
+// missingSnap is a snap required by a validation set that is not part
+// of the scenario.
+type missingSnap struct {
+	SnapName      string `json:"snap-name"`
+	ValidationSet string `json:"validation-set"`
+	// Revision is the required revision, if any
+	Revision int `json:"revision,omitempty"`
+}
+
+// validationErrors checks a snap of the scenario against the validation
+// sets: it must not be invalid and it must have the revision pinned, if
+// any. Local snaps have no revision.
+func (sc *scenario) validationErrors(name string, info *snap.Info) []string {
+	if len(sc.validationSets) == 0 {
+		return nil
+	}
+	ref, _ := resolveRef(name)
+	revision := readRevision(name, "revision")
+	var errs []string
+	for _, vs := range sc.validationSets {
+		for _, vsnap := range vs.Snaps() {
+			if vsnap.SnapID != ref.SnapID && vsnap.Name != info.SnapName() {
+				continue
+			}
+			key := validationSetKey(vs)
+			switch {
+			case vsnap.Presence == asserts.PresenceInvalid:
+				errs = append(errs, fmt.Sprintf("invalid in %s", key))
+			case vsnap.Revision > 0 && vsnap.Revision != revision:
+				rev := "local"
+				if revision >= 0 {
+					rev = strconv.Itoa(revision)
+				}
+				errs = append(errs, fmt.Sprintf("revision %s instead of %d required by %s", rev, vsnap.Revision, key))
+			}
+		}
+	}
+	return errs
+}
+
+// missingRequired returns the snaps required by the validation sets that
+// are not part of the scenario.
+func (sc *scenario) missingRequired() []missingSnap {
+	present := make(map[string]bool)
+	for _, info := range sc.infos {
+		present[info.SnapName()] = true
+	}
+	// the system snaps
+	for _, mocked := range sc.installed {
+		present[mocked.info.SnapName()] = true
+	}
+	var missing []missingSnap
+	for _, vs := range sc.validationSets {
+		for _, vsnap := range vs.Snaps() {
+			if vsnap.Presence != asserts.PresenceRequired || present[vsnap.Name] {
+				continue
+			}
+			missing = append(missing, missingSnap{
+				SnapName:      vsnap.Name,
+				ValidationSet: validationSetKey(vs),
+				Revision:      vsnap.Revision,
+			})
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].SnapName != missing[j].SnapName {
+			return missing[i].SnapName < missing[j].SnapName
+		}
+		return missing[i].ValidationSet < missing[j].ValidationSet
+	})
+	return missing
+}
+
+// checkInstall checks the installation of a snap of the scenario, also
+// against the validation sets, see refusal for enforce mode.
+func (sc *scenario) checkInstall(name string, mocked *mockedSnap) installation {
+	inst := checkInstall(sc.modelAs, mocked.info, mocked.decl)
+	inst.ValidationErrors = sc.validationErrors(name, mocked.info)
+	return inst
+}
+
+// refusal returns, in enforce mode, the installation report for a snap
+// of the scenario violating the validation sets, otherwise nil. Such
+// snaps are refused: they are not installed and so take no part in
+// connections.
+func (sc *scenario) refusal(name string) *installation {
+	if !sc.enforceValidation {
+		return nil
+	}
+	info := sc.infos[name]
+	verrs := sc.validationErrors(name, info)
+	if len(verrs) == 0 {
+		return nil
+	}
+	inst := &installation{
+		SnapName:         info.SnapName(),
+		Error:            fmt.Sprintf("cannot install snap %q: %s", info.SnapName(), strings.Join(verrs, ", ")),
+		ValidationErrors: verrs,
+	}
+	if err := checkArchitecture(info); err != nil {
+		inst.ArchitectureError = err.Error()
+	}
+	return inst
+}
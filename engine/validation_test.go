@@ -0,0 +1,283 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/snap"
+)
+
+const (
+	fooSnapID = "foosnapidfoosnapidfoosnapidfoosn"
+	barSnapID = "barsnapidbarsnapidbarsnapidbarsn"
+	bazSnapID = "bazsnapidbazsnapidbazsnapidbazsn"
+)
+
+// validationSet signs a validation-set assertion with the given snaps,
+// each as name, snap-id, presence and revision ("" for none).
+func validationSet(t *testing.T, storeSigning *assertstest.StoreStack, name string, snaps ...[4]string) *asserts.ValidationSet {
+	t.Helper()
+	var vsnaps []interface{}
+	for _, s := range snaps {
+		vsnap := map[string]interface{}{
+			"name":     s[0],
+			"id":       s[1],
+			"presence": s[2],
+		}
+		if s[3] != "" {
+			vsnap["revision"] = s[3]
+		}
+		vsnaps = append(vsnaps, vsnap)
+	}
+	a, err := storeSigning.Sign(asserts.ValidationSetType, map[string]interface{}{
+		"authority-id": "canonical",
+		"account-id":   "canonical",
+		"series":       "16",
+		"name":         name,
+		"sequence":     "1",
+		"snaps":        vsnaps,
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.(*asserts.ValidationSet)
+}
+
+func writeAssertions(t *testing.T, fn string, as ...asserts.Assertion) {
+	t.Helper()
+	f, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	enc := asserts.NewEncoder(f)
+	for _, a := range as {
+		if err := enc.Encode(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadValidationSets(t *testing.T) {
+	dir := t.TempDir()
+	storeSigning := assertstest.NewStoreStack("canonical", nil)
+	vs1 := validationSet(t, storeSigning, "one", [4]string{"foo", fooSnapID, "required", "3"})
+	vs2 := validationSet(t, storeSigning, "two", [4]string{"bar", barSnapID, "invalid", ""})
+	vs3 := validationSet(t, storeSigning, "three", [4]string{"baz", bazSnapID, "optional", ""})
+
+	both := filepath.Join(dir, "both.assert")
+	writeAssertions(t, both, vs1, storeSigning.StoreAccountKey(""), vs2)
+	single := filepath.Join(dir, "single.assert")
+	writeAssertions(t, single, vs3)
+
+	sets, err := readValidationSets([]string{both, single})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, vs := range sets {
+		keys = append(keys, validationSetKey(vs))
+	}
+	want := []string{"canonical/one=1", "canonical/two=1", "canonical/three=1"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("expected %v, got %v", want, keys)
+	}
+
+	none := filepath.Join(dir, "none.assert")
+	writeAssertions(t, none, storeSigning.StoreAccountKey(""))
+	_, err = readValidationSets([]string{none})
+	if err == nil || !strings.Contains(err.Error(), "no validation-set assertion in") {
+		t.Errorf("expected no validation-set error, got %v", err)
+	}
+
+	_, err = readValidationSets([]string{filepath.Join(dir, "missing.assert")})
+	if !os.IsNotExist(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestPinnedRevisions(t *testing.T) {
+	storeSigning := assertstest.NewStoreStack("canonical", nil)
+	vs1 := validationSet(t, storeSigning, "one",
+		[4]string{"foo", fooSnapID, "required", "3"},
+		[4]string{"bar", barSnapID, "optional", ""},
+		[4]string{"baz", bazSnapID, "invalid", ""},
+	)
+	vs2 := validationSet(t, storeSigning, "two",
+		[4]string{"foo", fooSnapID, "optional", "3"},
+		[4]string{"bar", barSnapID, "required", "8"},
+	)
+	pinned, err := pinnedRevisions([]*asserts.ValidationSet{vs1, vs2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"foo": 3, "bar": 8}
+	if !reflect.DeepEqual(pinned, want) {
+		t.Errorf("expected %v, got %v", want, pinned)
+	}
+
+	conflicting := validationSet(t, storeSigning, "three",
+		[4]string{"foo", fooSnapID, "required", "4"},
+	)
+	_, err = pinnedRevisions([]*asserts.ValidationSet{vs1, conflicting})
+	const wantErr = "validation sets canonical/one=1 and canonical/three=1 pin foo to different revisions 3 and 4"
+	if err == nil || err.Error() != wantErr {
+		t.Errorf("expected %q, got %v", wantErr, err)
+	}
+}
+
+// validationScenario sets up snap directories for the snaps with the
+// given revisions, -1 for local ones, and a scenario with them.
+func validationScenario(t *testing.T, revisions map[string]int, snapIDs map[string]string, sets ...*asserts.ValidationSet) *scenario {
+	t.Helper()
+	chdir(t, t.TempDir())
+	sc := &scenario{
+		infos:          make(map[string]*snap.Info),
+		installed:      make(map[string]*mockedSnap),
+		validationSets: sets,
+	}
+	for name, revision := range revisions {
+		if err := os.Mkdir(name, 0755); err != nil {
+			t.Fatal(err)
+		}
+		ref := &snapRef{SnapName: name, SnapID: snapIDs[name], PublisherID: "pub"}
+		if err := writeJSON(name, ".snap.json", ref); err != nil {
+			t.Fatal(err)
+		}
+		if revision >= 0 {
+			if err := writeRevision(name, "revision", revision); err != nil {
+				t.Fatal(err)
+			}
+		}
+		info, err := snap.InfoFromSnapYaml([]byte("name: " + name + "\nversion: 1\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sc.infos[name] = info
+		sc.snaps = append(sc.snaps, name)
+	}
+	return sc
+}
+
+func TestValidationErrors(t *testing.T) {
+	storeSigning := assertstest.NewStoreStack("canonical", nil)
+	vs := validationSet(t, storeSigning, "one",
+		[4]string{"foo", fooSnapID, "required", "3"},
+		[4]string{"bar", barSnapID, "invalid", ""},
+		[4]string{"baz", bazSnapID, "required", "5"},
+	)
+	sc := validationScenario(t, map[string]int{
+		"foo": 3,
+		"bar": 1,
+		"baz": -1,
+	}, map[string]string{
+		"foo": fooSnapID,
+		// matched by name as well
+		"bar": "other-id",
+		"baz": bazSnapID,
+	}, vs)
+
+	for name, want := range map[string][]string{
+		"foo": nil,
+		"bar": {"invalid in canonical/one=1"},
+		"baz": {"revision local instead of 5 required by canonical/one=1"},
+	} {
+		got := sc.validationErrors(name, sc.infos[name])
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %q, got %q", name, want, got)
+		}
+	}
+
+	if err := writeRevision("foo", "revision", 4); err != nil {
+		t.Fatal(err)
+	}
+	got := sc.validationErrors("foo", sc.infos["foo"])
+	want := []string{"revision 4 instead of 3 required by canonical/one=1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestMissingRequired(t *testing.T) {
+	storeSigning := assertstest.NewStoreStack("canonical", nil)
+	vs1 := validationSet(t, storeSigning, "one",
+		[4]string{"foo", fooSnapID, "required", "3"},
+		[4]string{"baz", bazSnapID, "required", ""},
+	)
+	vs2 := validationSet(t, storeSigning, "two",
+		[4]string{"bar", barSnapID, "required", "2"},
+		[4]string{"baz", bazSnapID, "optional", ""},
+	)
+	sc := validationScenario(t, map[string]int{"foo": 3}, map[string]string{"foo": fooSnapID}, vs2, vs1)
+
+	want := []missingSnap{
+		{SnapName: "bar", ValidationSet: "canonical/two=1", Revision: 2},
+		{SnapName: "baz", ValidationSet: "canonical/one=1"},
+	}
+	if got := sc.missingRequired(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestRefusal(t *testing.T) {
+	storeSigning := assertstest.NewStoreStack("canonical", nil)
+	vs := validationSet(t, storeSigning, "one",
+		[4]string{"foo", fooSnapID, "required", "3"},
+		[4]string{"bar", barSnapID, "invalid", ""},
+	)
+	sc := validationScenario(t, map[string]int{
+		"foo": 3,
+		"bar": 1,
+	}, map[string]string{
+		"foo": fooSnapID,
+		"bar": barSnapID,
+	}, vs)
+
+	// only refused in enforce mode
+	if inst := sc.refusal("bar"); inst != nil {
+		t.Errorf("unexpected refusal in monitor mode %+v", inst)
+	}
+
+	sc.enforceValidation = true
+	if inst := sc.refusal("foo"); inst != nil {
+		t.Errorf("unexpected refusal %+v", inst)
+	}
+	inst := sc.refusal("bar")
+	if inst == nil {
+		t.Fatalf("expected bar to be refused")
+	}
+	want := installation{
+		SnapName:         "bar",
+		Error:            `cannot install snap "bar": invalid in canonical/one=1`,
+		ValidationErrors: []string{"invalid in canonical/one=1"},
+	}
+	if !reflect.DeepEqual(*inst, want) {
+		t.Errorf("expected %+v, got %+v", want, *inst)
+	}
+}
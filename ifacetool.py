@@ -18,8 +18,22 @@ import click
 from ops import (
     Fetcher,
     auto_connections_op,
+    base_declaration_op,
+    check_meta_op,
+    eval_attrs_op,
+    connection_matrix_op,
     fetch_op,
+    import_op,
+    interfaces_op,
+    mock_store_op,
+    over_grant_op,
+    replay_op,
+    set_export,
     snap_at_rev,
+    suggest_op,
+    reverse_lookup_op,
+    rule_coverage_op,
+    system_graph_op,
 )
 
 
@@ -34,6 +48,15 @@ class SnapAtRevType(click.ParamType):
         if len(parts) == 1:
             return snap_at_rev(value)
 
+        if not parts[1].isdigit():
+            # <track>/<risk>/<branch> with optional track and branch
+            components = parts[1].split("/")
+            if len(components) > 3 or not all(components):
+                self.fail(
+                    f"{parts[1]!r} in {value!r} is not a valid channel", param, ctx
+                )
+            return snap_at_rev(parts[0], channel=parts[1])
+
         try:
             revno = int(parts[1])
             if revno <= 0:
@@ -45,33 +68,178 @@ class SnapAtRevType(click.ParamType):
 
 SNAP_AT_REV = SnapAtRevType()
 
+GRAPH_FORMATS = ["text", "json", "dot", "mermaid"]
+
+SYSTEM_FLAVOURS = ["snapd", "core", "core16", "core18", "core20", "core22", "core24"]
+
 
 @click.group()
-def cli():
-    pass
+@click.option(
+    "--export", type=click.Path(dir_okay=False), default=None, metavar="<bundle>"
+)
+def cli(export):
+    if export:
+        set_export(export)
 
 
 @cli.command(short_help=fetch_op.__doc__, help=fetch_op.__doc__)
 @click.option("--meta/--no-meta", default=True)
 @click.option("--decls/--no-decls", default=True)
+@click.option("--store-url", type=str, default=None, metavar="<url>")
+@click.option("--refresh", is_flag=True, default=False)
+@click.option("-j", "--jobs", type=int, default=None, metavar="<n>")
+@click.option(
+    "--cache", type=click.Path(file_okay=False), default=None, metavar="<dir>"
+)
+@click.option("--arch", type=str, default=None, metavar="<architecture>")
+@click.option("--closure", is_flag=True, default=False)
+@click.option("--platform", type=str, multiple=True, metavar="<snap>")
+@click.option(
+    "--validation-set",
+    type=click.Path(exists=True, dir_okay=False),
+    multiple=True,
+    metavar="<file>",
+)
 @click.argument(
-    "snaps", nargs=-1, type=SNAP_AT_REV, required=True, metavar="<snap>[@<rev>]..."
+    "snaps", nargs=-1, type=SNAP_AT_REV, metavar="<snap>[@<rev>|@<channel>]..."
 )
-def fetch(snaps, meta, decls):
-    f = Fetcher()
-    fetch_op(snaps, meta=meta, decls=decls, f=f)
+def fetch(
+    snaps,
+    meta,
+    decls,
+    store_url,
+    refresh,
+    jobs,
+    cache,
+    arch,
+    closure,
+    platform,
+    validation_set,
+):
+    if not snaps and (closure or not (refresh or validation_set)):
+        raise click.UsageError("no snaps given")
+    if platform and not closure:
+        raise click.UsageError("--platform only makes sense with --closure")
+    f = Fetcher(store_url, cache)
+    fetch_op(
+        snaps,
+        meta=meta,
+        decls=decls,
+        refresh=refresh,
+        jobs=jobs,
+        architecture=arch,
+        closure=closure,
+        platform=platform,
+        validation_sets=validation_set,
+        f=f,
+    )
+
+
+@cli.command(short_help=mock_store_op.__doc__, help=mock_store_op.__doc__)
+@click.option("--addr", type=str, default=None, metavar="<host>:<port>")
+@click.argument(
+    "directory", type=click.Path(exists=True, file_okay=False), metavar="<dir>"
+)
+def mock_store(directory, addr):
+    mock_store_op(directory, addr=addr)
+
+
+@cli.command(short_help=replay_op.__doc__, help=replay_op.__doc__)
+@click.argument(
+    "bundle", type=click.Path(exists=True, dir_okay=False), metavar="<bundle>"
+)
+def replay(bundle):
+    replay_op(bundle)
+
+
+@cli.command("import", short_help=import_op.__doc__, help=import_op.__doc__)
+@click.argument(
+    "bundle", type=click.Path(exists=True, dir_okay=False), metavar="<bundle>"
+)
+@click.argument("directory", type=click.Path(file_okay=False), metavar="<dir>")
+def import_bundle(bundle, directory):
+    import_op(bundle, directory)
+
+
+def scenario_options(f):
+    "options common to the simulations"
+    options = [
+        click.option(
+            "--model", type=str, default="brand/model", metavar="<brand>/<model>"
+        ),
+        click.option("--store", type=str, default=None, metavar="<store-id>"),
+        click.option("--classic", is_flag=True, default=False),
+        click.option("--system", type=click.Choice(SYSTEM_FLAVOURS), default="snapd"),
+        click.option("--arch", type=str, default="amd64", metavar="<architecture>"),
+        click.option(
+            "--base-declaration",
+            type=click.Path(exists=True, dir_okay=False),
+            default=None,
+            metavar="<file>",
+        ),
+        click.option(
+            "--validation-set",
+            type=click.Path(exists=True, dir_okay=False),
+            multiple=True,
+            metavar="<file>",
+        ),
+        click.option(
+            "--validation-mode",
+            type=click.Choice(["monitor", "enforce"]),
+            default="monitor",
+        ),
+        click.option("--deterministic/--no-deterministic", default=None),
+    ]
+    for option in reversed(options):
+        f = option(f)
+    return f
+
+
+def interface_option():
+    return click.option(
+        "-i", "--interface", type=str, default=None, metavar="<interface>"
+    )
+
+
+def format_option(formats):
+    return click.option(
+        "--format", "output_format", type=click.Choice(formats), default="text"
+    )
+
+
+def default_deterministic(deterministic, output_format):
+    if deterministic is None:
+        # on by default for machine output
+        return output_format != "text"
+    return deterministic
 
 
 @cli.command(short_help=auto_connections_op.__doc__, help=auto_connections_op.__doc__)
-@click.option("--model", type=str, default="brand/model", metavar="<brand>/<model>")
-@click.option("--store", type=str, default=None, metavar="<store-id>")
-@click.option("--classic", is_flag=True, default=False)
-@click.option("-i", "--interface", type=str, default=None, metavar="<interface>")
+@scenario_options
+@interface_option()
+@format_option(GRAPH_FORMATS)
+@click.option("--system-slots", is_flag=True, default=False)
 @click.option("--candidates", is_flag=True, default=False)
+@click.option("--arity", is_flag=True, default=False)
 @click.argument("target-snap", type=str, required=True, metavar="<target-snap>")
 @click.argument("context-snaps", type=str, nargs=-1, metavar="<context-snap>...")
 def auto_connections(
-    target_snap, context_snaps, interface, candidates, model, store, classic
+    target_snap,
+    context_snaps,
+    interface,
+    candidates,
+    arity,
+    model,
+    store,
+    classic,
+    system,
+    system_slots,
+    arch,
+    base_declaration,
+    validation_set,
+    validation_mode,
+    output_format,
+    deterministic,
 ):
     f = Fetcher()
     auto_connections_op(
@@ -79,12 +247,339 @@ def auto_connections(
         context_snaps,
         interface=interface,
         candidates=candidates,
+        arity=arity,
+        model=model,
+        store=store,
+        classic=classic,
+        system=system,
+        system_slots=system_slots,
+        architecture=arch,
+        base_declaration=base_declaration,
+        validation_sets=validation_set,
+        validation_mode=validation_mode,
+        output_format=output_format,
+        deterministic=default_deterministic(deterministic, output_format),
+        f=f,
+    )
+
+
+@cli.command(short_help=system_graph_op.__doc__, help=system_graph_op.__doc__)
+@scenario_options
+@interface_option()
+@format_option(GRAPH_FORMATS)
+@click.argument("snaps", type=str, nargs=-1, required=True, metavar="<snap>...")
+def system_graph(
+    snaps,
+    interface,
+    model,
+    store,
+    classic,
+    system,
+    arch,
+    base_declaration,
+    validation_set,
+    validation_mode,
+    output_format,
+    deterministic,
+):
+    f = Fetcher()
+    system_graph_op(
+        snaps,
+        interface=interface,
         model=model,
         store=store,
         classic=classic,
+        system=system,
+        architecture=arch,
+        base_declaration=base_declaration,
+        validation_sets=validation_set,
+        validation_mode=validation_mode,
+        output_format=output_format,
+        deterministic=default_deterministic(deterministic, output_format),
         f=f,
     )
 
 
+@cli.command(short_help=reverse_lookup_op.__doc__, help=reverse_lookup_op.__doc__)
+@scenario_options
+@format_option(["text", "json"])
+@click.argument("slot", type=str, required=True, metavar="<snap>:<slot>")
+@click.argument("snaps", type=str, nargs=-1, required=True, metavar="<snap>...")
+def reverse_lookup(
+    slot,
+    snaps,
+    model,
+    store,
+    classic,
+    system,
+    arch,
+    base_declaration,
+    validation_set,
+    validation_mode,
+    output_format,
+    deterministic,
+):
+    f = Fetcher()
+    reverse_lookup_op(
+        slot,
+        snaps,
+        model=model,
+        store=store,
+        classic=classic,
+        system=system,
+        architecture=arch,
+        base_declaration=base_declaration,
+        validation_sets=validation_set,
+        validation_mode=validation_mode,
+        output_format=output_format,
+        deterministic=default_deterministic(deterministic, output_format),
+        f=f,
+    )
+
+
+@cli.command(
+    short_help=connection_matrix_op.__doc__, help=connection_matrix_op.__doc__
+)
+@scenario_options
+@interface_option()
+@format_option(["text", "json", "csv"])
+@click.option("--include-system", is_flag=True, default=False)
+@click.argument("snaps", type=str, nargs=-1, required=True, metavar="<snap>...")
+def connection_matrix(
+    snaps,
+    interface,
+    include_system,
+    model,
+    store,
+    classic,
+    system,
+    arch,
+    base_declaration,
+    validation_set,
+    validation_mode,
+    output_format,
+    deterministic,
+):
+    f = Fetcher()
+    connection_matrix_op(
+        snaps,
+        interface=interface,
+        include_system=include_system,
+        model=model,
+        store=store,
+        classic=classic,
+        system=system,
+        architecture=arch,
+        base_declaration=base_declaration,
+        validation_sets=validation_set,
+        validation_mode=validation_mode,
+        output_format=output_format,
+        deterministic=default_deterministic(deterministic, output_format),
+        f=f,
+    )
+
+
+@cli.command(short_help=suggest_op.__doc__, help=suggest_op.__doc__)
+@scenario_options
+@format_option(["text", "json"])
+@click.option("--outcome", type=click.Choice(["auto", "connect"]), default="auto")
+@click.option("--all", "show_all", is_flag=True, default=False)
+@click.argument("plug", type=str, required=True, metavar="<snap>:<plug>")
+@click.argument("slot", type=str, required=True, metavar="<snap>:<slot>")
+@click.argument("context-snaps", type=str, nargs=-1, metavar="<context-snap>...")
+def suggest(
+    plug,
+    slot,
+    context_snaps,
+    outcome,
+    show_all,
+    model,
+    store,
+    classic,
+    system,
+    arch,
+    base_declaration,
+    validation_set,
+    validation_mode,
+    output_format,
+    deterministic,
+):
+    f = Fetcher()
+    suggest_op(
+        plug,
+        slot,
+        context_snaps,
+        outcome=outcome,
+        show_all=show_all,
+        model=model,
+        store=store,
+        classic=classic,
+        system=system,
+        architecture=arch,
+        base_declaration=base_declaration,
+        validation_sets=validation_set,
+        validation_mode=validation_mode,
+        output_format=output_format,
+        deterministic=default_deterministic(deterministic, output_format),
+        f=f,
+    )
+
+
+@cli.command(short_help=over_grant_op.__doc__, help=over_grant_op.__doc__)
+@scenario_options
+@format_option(["text", "json"])
+@click.option("--plugs", "proposed_plugs", type=click.Path(exists=True, dir_okay=False))
+@click.option("--slots", "proposed_slots", type=click.Path(exists=True, dir_okay=False))
+@click.argument("snap", type=str, required=True, metavar="<snap>")
+@click.argument("context-snaps", type=str, nargs=-1, metavar="<context-snap>...")
+def over_grant(
+    snap,
+    proposed_plugs,
+    proposed_slots,
+    context_snaps,
+    model,
+    store,
+    classic,
+    system,
+    arch,
+    base_declaration,
+    validation_set,
+    validation_mode,
+    output_format,
+    deterministic,
+):
+    if not proposed_plugs and not proposed_slots:
+        raise click.UsageError("at least one of --plugs or --slots is required")
+    f = Fetcher()
+    over_grant_op(
+        snap,
+        proposed_plugs,
+        proposed_slots,
+        context_snaps,
+        model=model,
+        store=store,
+        classic=classic,
+        system=system,
+        architecture=arch,
+        base_declaration=base_declaration,
+        validation_sets=validation_set,
+        validation_mode=validation_mode,
+        output_format=output_format,
+        deterministic=default_deterministic(deterministic, output_format),
+        f=f,
+    )
+
+
+@cli.command(short_help=rule_coverage_op.__doc__, help=rule_coverage_op.__doc__)
+@scenario_options
+@format_option(["text", "json"])
+@click.option("--include-base", is_flag=True, default=False)
+@click.option("--dead", is_flag=True, default=False)
+@click.option("--matches", is_flag=True, default=False)
+@click.argument("snaps", type=str, nargs=-1, required=True, metavar="<snap>...")
+def rule_coverage(
+    snaps,
+    include_base,
+    dead,
+    matches,
+    model,
+    store,
+    classic,
+    system,
+    arch,
+    base_declaration,
+    validation_set,
+    validation_mode,
+    output_format,
+    deterministic,
+):
+    f = Fetcher()
+    rule_coverage_op(
+        snaps,
+        include_base=include_base,
+        dead=dead,
+        matches=matches,
+        model=model,
+        store=store,
+        classic=classic,
+        system=system,
+        architecture=arch,
+        base_declaration=base_declaration,
+        validation_sets=validation_set,
+        validation_mode=validation_mode,
+        output_format=output_format,
+        deterministic=default_deterministic(deterministic, output_format),
+        f=f,
+    )
+
+
+@cli.command(short_help=base_declaration_op.__doc__, help=base_declaration_op.__doc__)
+@format_option(["text", "json"])
+@click.option(
+    "--base-declaration",
+    type=click.Path(exists=True, dir_okay=False),
+    default=None,
+    metavar="<file>",
+)
+@click.argument("interfaces", type=str, nargs=-1, metavar="<interface>...")
+def base_declaration(interfaces, base_declaration, output_format):
+    base_declaration_op(
+        interfaces, base_declaration=base_declaration, output_format=output_format
+    )
+
+
+@cli.command(short_help=interfaces_op.__doc__, help=interfaces_op.__doc__)
+@format_option(["text", "json"])
+@click.option(
+    "--base-declaration",
+    type=click.Path(exists=True, dir_okay=False),
+    default=None,
+    metavar="<file>",
+)
+@click.argument("interface", type=str, required=False, metavar="[<interface>]")
+def interfaces(interface, base_declaration, output_format):
+    interfaces_op(
+        interface, base_declaration=base_declaration, output_format=output_format
+    )
+
+
+@cli.command(short_help=check_meta_op.__doc__, help=check_meta_op.__doc__)
+@format_option(["text", "json"])
+@click.option("--warnings/--no-warnings", default=True)
+@click.argument("snaps", type=str, nargs=-1, required=True, metavar="<snap>...")
+def check_meta(snaps, warnings, output_format):
+    check_meta_op(snaps, warnings=warnings, output_format=output_format)
+
+
+@cli.command(short_help=eval_attrs_op.__doc__, help=eval_attrs_op.__doc__)
+@interface_option()
+@format_option(["text", "json"])
+@click.option(
+    "--plug",
+    "plug_attrs",
+    type=click.Path(exists=True, dir_okay=False),
+    default=None,
+    metavar="<attrs.json>",
+)
+@click.option(
+    "--slot",
+    "slot_attrs",
+    type=click.Path(exists=True, dir_okay=False),
+    default=None,
+    metavar="<attrs.json>",
+)
+@click.argument(
+    "constraints", type=click.Path(exists=True, dir_okay=False), metavar="<file>"
+)
+def eval_attrs(constraints, plug_attrs, slot_attrs, interface, output_format):
+    eval_attrs_op(
+        constraints,
+        plug_attrs,
+        slot_attrs,
+        interface=interface,
+        output_format=output_format,
+    )
+
+
 if __name__ == "__main__":
     cli()
@@ -16,8 +16,19 @@
 
 import sys
 
-from .fetch import Fetcher, fetch_op, snap_at_rev  # noqa: F401
-from .simulation import auto_connections_op  # noqa: F401
+from .bundle import import_op, replay_op  # noqa: F401
+from .corpus import connection_matrix_op, reverse_lookup_op  # noqa: F401
+from .fetch import Fetcher, fetch_op, mock_store_op, snap_at_rev  # noqa: F401
+from .meta import check_meta_op  # noqa: F401
+from .rules import (  # noqa: F401
+    base_declaration_op,
+    eval_attrs_op,
+    interfaces_op,
+    over_grant_op,
+    rule_coverage_op,
+    suggest_op,
+)
+from .simulation import auto_connections_op, set_export, system_graph_op  # noqa: F401
 
 if not sys.warnoptions:
     import warnings
@@ -0,0 +1,55 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2026 Canonical Ltd.
+#
+# This program is free software; you can redistribute it and/or
+# modify it under the terms of the GNU Lesser General Public
+# License version 3 as published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+# Lesser General Public License for more details.
+#
+# You should have received a copy of the GNU Lesser General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import difflib
+import json
+import os
+import shlex
+
+from .engine import engine, engine_pgm
+
+
+def prversions(what, versions):
+    print(f"{what}: engine {versions['engine-version']}", end="")
+    print(f" snapd {versions['snapd-version']}")
+
+
+def import_op(bundle, directory):
+    "extract a bundle exported with --export to re-run it by hand"
+    meta = engine("import", bundle=bundle, dir=directory)
+    prversions("exported with", meta)
+    print(f"op: {meta['op']}")
+    snaps_dir = os.path.join(directory, "snaps")
+    param = shlex.quote(json.dumps(meta["params"]))
+    print(f"  cd {snaps_dir} && {engine_pgm()} {meta['op']} {param}")
+
+
+def replay_op(bundle):
+    "re-run offline a simulation exported with --export and compare"
+    res = engine("replay", bundle=bundle)
+    prversions("exported with", res["bundle"])
+    prversions("replayed with", res)
+    print(f"op: {res['bundle']['op']}")
+    if res["identical"]:
+        print("output identical to the exported one")
+        return
+    diff = difflib.unified_diff(
+        res["recorded"].splitlines(keepends=True),
+        res["output"].splitlines(keepends=True),
+        fromfile="exported",
+        tofile="replayed",
+    )
+    print("".join(diff), end="")
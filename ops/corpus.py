@@ -0,0 +1,177 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2026 Canonical Ltd.
+#
+# This program is free software; you can redistribute it and/or
+# modify it under the terms of the GNU Lesser General Public
+# License version 3 as published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+# Lesser General Public License for more details.
+#
+# You should have received a copy of the GNU Lesser General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import csv
+import sys
+
+from .simulation import prinstallation, scenario_params, simulate
+
+
+def reverse_lookup_op(
+    slot,
+    snaps,
+    model,
+    store,
+    classic,
+    system,
+    architecture,
+    base_declaration,
+    validation_sets,
+    validation_mode,
+    output_format,
+    deterministic,
+    f,
+):
+    "list the plugs in a corpus of snaps that could connect to a slot"
+    # prepare
+    for name in set(snaps):
+        f.snap_ids(name)
+    params = scenario_params(
+        model=model,
+        store=store,
+        classic=classic,
+        system=system,
+        architecture=architecture,
+        base_declaration=base_declaration,
+        validation_sets=validation_sets,
+        validation_mode=validation_mode,
+        deterministic=deterministic,
+    )
+    params["slot"] = slot
+    params["snaps"] = snaps
+    out = simulate("reverse-lookup", params, output_format)
+    if out is None:
+        return
+
+    for inst in out["installing"]:
+        if inst["error"] or inst.get("architecture-error"):
+            prinstallation(inst)
+
+    print(f"{out['slot']['snap']}:{out['slot']['slot']} ({out['interface']})")
+    plugs = out["plugs"] or []
+    for verdict in ("auto", "manual", "denied"):
+        prverdicts(verdict, [p for p in plugs if p["verdict"] == verdict])
+
+
+def prverdicts(verdict, pairs):
+    if not pairs:
+        return
+    print(f"{verdict}:")
+    reason = None
+    for pair in pairs:
+        print(f"  {pair['plug']['snap']}:{pair['plug']['plug']}")
+        if pair.get("reason"):
+            if pair["reason"] != reason:
+                reason = pair["reason"]
+                print(f"    => {reason}")
+            else:
+                print("    => //")
+            cands = pair.get("candidates")
+            if cands:
+                slots = ", ".join(f"{c['snap']}:{c['slot']}" for c in cands)
+                print(f"    candidates: {slots}")
+        elif pair.get("slots-per-plug-any"):
+            print("    => slots-per-plug:*")
+
+
+MATRIX_COLUMNS = [
+    "interface",
+    "plug-snap",
+    "plug",
+    "slot-snap",
+    "slot",
+    "allow-connection",
+    "allow-auto-connection",
+    "verdict",
+    "connect-error",
+    "auto-connect-error",
+]
+
+
+def matrix_row(pair):
+    return {
+        "interface": pair["interface"],
+        "plug-snap": pair["plug"]["snap"],
+        "plug": pair["plug"]["plug"],
+        "slot-snap": pair["slot"]["snap"],
+        "slot": pair["slot"]["slot"],
+        "allow-connection": pair["allow-connection"],
+        "allow-auto-connection": pair["allow-auto-connection"],
+        "verdict": pair["verdict"],
+        "connect-error": pair.get("connect-error", ""),
+        "auto-connect-error": pair.get("auto-connect-error", ""),
+    }
+
+
+def connection_matrix_op(
+    snaps,
+    interface,
+    include_system,
+    model,
+    store,
+    classic,
+    system,
+    architecture,
+    base_declaration,
+    validation_sets,
+    validation_mode,
+    output_format,
+    deterministic,
+    f,
+):
+    "evaluate the rules for all plug/slot pairs across a corpus of snaps"
+    # prepare
+    for name in set(snaps):
+        f.snap_ids(name)
+    params = scenario_params(
+        model=model,
+        store=store,
+        classic=classic,
+        system=system,
+        architecture=architecture,
+        base_declaration=base_declaration,
+        validation_sets=validation_sets,
+        validation_mode=validation_mode,
+        deterministic=deterministic,
+    )
+    params["snaps"] = snaps
+    params["include-system"] = include_system
+    if interface:
+        params["interface"] = interface
+    out = simulate("connection-matrix", params, output_format)
+    if out is None:
+        return
+
+    pairs = out["pairs"] or []
+    if output_format == "csv":
+        w = csv.DictWriter(sys.stdout, fieldnames=MATRIX_COLUMNS)
+        w.writeheader()
+        for pair in pairs:
+            w.writerow(matrix_row(pair))
+        return
+
+    for inst in out["installing"]:
+        if inst["error"] or inst.get("architecture-error"):
+            prinstallation(inst)
+
+    iface = None
+    for pair in pairs:
+        if pair["interface"] != iface:
+            iface = pair["interface"]
+            print(f"{iface}:")
+        plug = f"{pair['plug']['snap']}:{pair['plug']['plug']}"
+        slot = f"{pair['slot']['snap']}:{pair['slot']['slot']}"
+        print(f"  {slot} > {plug}: {pair['verdict']}")
@@ -21,10 +21,15 @@ import os
 import subprocess
 
 
-def engine(op, **params):
+def engine_pgm():
     engpgm = os.path.join(__path__[0], "..", "ifacetool-engine")
     if not os.path.isfile(engpgm):
         engpgm = os.path.basename(engpgm)
+    return engpgm
+
+
+def engine_raw(op, **params):
+    engpgm = engine_pgm()
     param = json.dumps(params)
     try:
         out = subprocess.run(
@@ -36,6 +41,22 @@ def engine(op, **params):
         raise Exception("{}, err: {}".format(pe, pe.stderr.decode("utf8")))
     if out.startswith(b"AppArmor status:"):
         out = out[out.find(b"\n") + 1 :]
+    return out
+
+
+def engine(op, **params):
+    out = engine_raw(op, **params)
     if not out:
         return None
     return json.loads(out)
+
+
+def engine_run(op, **params):
+    "run the engine op with its output going directly to stdout"
+    param = json.dumps(params)
+    try:
+        subprocess.run([engine_pgm(), op, param], check=True)
+    except KeyboardInterrupt:
+        pass
+    except subprocess.CalledProcessError as pe:
+        raise Exception(str(pe))
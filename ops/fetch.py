@@ -15,119 +15,257 @@
 # along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
 import os
-import tempfile
-import subprocess
 import json
 import yaml
 from collections import namedtuple
 
 from craft_store import UbuntuOneStoreClient, endpoints
 
-from .engine import engine
+from .engine import engine, engine_run
 
 
 class Fetcher:
-    def __init__(self):
-        self.c = UbuntuOneStoreClient(
-            base_url="https://dashboard.snapcraft.io",
-            storage_base_url="https://upload.apps.staging.ubuntu.com",
-            auth_url="https://login.ubuntu.com",
-            endpoints=endpoints.U1_SNAP_STORE,
-            application_name="snapcraft",  # reuse login with snapcraft
-            environment_auth="SNAPCRAFT_STORE_CREDENTIALS",
-            user_agent="ifacetool",
-        )
+    "fetches from the store via the engine"
+
+    def __init__(self, store_url=None, cache=None):
+        if store_url is None:
+            store_url = os.environ.get("IFACETOOL_STORE_URL")
+        if cache is None:
+            cache = os.environ.get("IFACETOOL_CACHE")
+        self.store_url = store_url
+        self.cache = cache
+        self.c = None
+        if not store_url:
+            # only used to get the credentials, also from the keyring
+            self.c = UbuntuOneStoreClient(
+                base_url="https://dashboard.snapcraft.io",
+                storage_base_url="https://upload.apps.staging.ubuntu.com",
+                auth_url="https://login.ubuntu.com",
+                endpoints=endpoints.U1_SNAP_STORE,
+                application_name="snapcraft",  # reuse login with snapcraft
+                environment_auth="SNAPCRAFT_STORE_CREDENTIALS",
+                user_agent="ifacetool",
+            )
+        self._credentials = None
+
+    def engine_params(self):
+        "store parameters for the engine fetch op"
+        if self.store_url:
+            # e.g. a mock-store, no authentication
+            return {"store-url": self.store_url}
+        if self._credentials is None:
+            self._credentials = self.c._auth.get_credentials()
+        return {"credentials": self._credentials}
+
+    def fetch(
+        self,
+        snaps,
+        meta,
+        decls,
+        refresh=False,
+        jobs=None,
+        architecture=None,
+        closure=False,
+        platform=(),
+        validation_sets=(),
+    ):
+        params = self.engine_params()
+        if validation_sets:
+            params["validation-sets"] = list(validation_sets)
+        if closure:
+            params["closure"] = True
+            params["platform"] = list(platform)
+        if architecture:
+            params["architecture"] = architecture
+        if self.cache:
+            params["cache"] = self.cache
+        if refresh:
+            params["refresh"] = True
+        if jobs:
+            params["jobs"] = jobs
+        return engine("fetch", snaps=snaps, meta=meta, decls=decls, **params)
 
     def snap_ids(self, name):
+        if name.endswith(".snap"):
+            # read directly by the engine, see resolveRef there
+            return None, None
         if "." in name or "/" in name:
             raise Exception(f"invalid snap name: {name}")
         info_fn = f"{name}/.snap.json"
-        if os.path.isfile(info_fn):
-            with open(info_fn) as info_f:
-                info = json.load(info_f)
-        else:
-            rsp = self.c.request(
-                "GET", f"https://dashboard.snapcraft.io/dev/api/snaps/info/{name}"
-            )
-            sto_info = rsp.json()
-            info = {
-                "snap-name": name,
-                "snap-id": sto_info["snap_id"],
-                "publisher-id": sto_info["publisher"]["id"],
-            }
-            os.makedirs(name, exist_ok=True)
-            with open(info_fn, "w") as info_f:
-                info_f.write(json.dumps(info, indent=2))
-                info_f.write("\n")
+        if not os.path.isfile(info_fn):
+            # creates dir <name> and caches values in <name>/.snap.json
+            self.fetch([{"name": name}], meta=False, decls=False)
+        with open(info_fn) as info_f:
+            info = json.load(info_f)
         return info["snap-id"], info["publisher-id"]
 
-    def fetch_metadata(self, snap_at_rev):
-        name, revno, _ = snap_at_rev
-        if revno is None:
-            revno = "latest"
-        rsp = self.c.request(
-            "GET",
-            f"https://dashboard.snapcraft.io/api/v2/snaps/{name}/revisions/{revno}?include-yaml=1",
-        )
-        rev_data = rsp.json()["revision"]
-        return rev_data["revision"], rev_data["snap-yaml"]
-
 
 snap_at_rev = namedtuple(
-    "snap_at_rev", ["name", "revision", "local_yaml"], defaults=[None, None]
+    "snap_at_rev",
+    [
+        "name",
+        "revision",
+        "local_yaml",
+        "local_gadget_yaml",
+        "local_hooks",
+        "channel",
+        "architecture",
+    ],
+    defaults=[None, None, None, None, None, None],
 )
 
 
-def fetch_op(snaps, *, f, meta=True, decls=True):
+def fetch_op(
+    snaps,
+    *,
+    f,
+    meta=True,
+    decls=True,
+    refresh=False,
+    jobs=None,
+    architecture=None,
+    closure=False,
+    platform=(),
+    validation_sets=(),
+):
     "fetch snap metadata and snap-declaration content"
-    snap_names = []
+    if not snaps and refresh:
+        snaps = fetched_snaps()
+    to_fetch = []
     for snap in snaps:
+        fsnap = {"name": snap.name}
         # local special case
         if snap.name.endswith((".yaml", ".snap")):
             revision = os.path.abspath(snap.name)
             snap = local_fetch(revision)
-        # creates dir <name> and caches values in <name>/.snap.json
-        f.snap_ids(snap.name)
-        snap_names.append(snap.name)
+            fsnap = {"name": snap.name, "local": True}
+            if meta:
+                os.makedirs(snap.name, exist_ok=True)
+                write_snap_file(snap.name, "snap.yaml", snap.local_yaml)
+                if snap.local_gadget_yaml is not None:
+                    write_snap_file(snap.name, "gadget.yaml", snap.local_gadget_yaml)
+                if snap.local_hooks:
+                    hooks = "".join(f"{hook}\n" for hook in snap.local_hooks)
+                    write_snap_file(snap.name, "hooks", hooks)
+                # revision is set to the local path
+                write_snap_file(snap.name, "revision", f"{revision}\n")
+        elif snap.revision is not None:
+            fsnap["revision"] = snap.revision
+        elif snap.channel is not None:
+            fsnap["channel"] = snap.channel
+            if snap.architecture is not None:
+                fsnap["architecture"] = snap.architecture
+        to_fetch.append(fsnap)
+
+    # snap ids, store metadata and declarations
+    res = f.fetch(
+        to_fetch,
+        meta=meta,
+        decls=decls,
+        refresh=refresh,
+        jobs=jobs,
+        architecture=architecture,
+        closure=closure,
+        platform=platform,
+        validation_sets=validation_sets,
+    )
+    if refresh:
+        prrefresh(res)
+    if closure:
+        prclosure(res)
+
+
+def write_snap_file(name, what, content):
+    "write a snap directory file, replacing any link into the fetch cache"
+    fn = f"{name}/{what}"
+    if os.path.islink(fn):
+        os.remove(fn)
+    with open(fn, "w") as f:
+        f.write(content)
+
+
+def fetched_snaps():
+    "snap directories in the current directory fetched from the store"
+    snaps = []
+    for name in sorted(os.listdir(".")):
+        if not os.path.isfile(f"{name}/.snap.json"):
+            continue
+        revision_fn = f"{name}/revision"
+        if os.path.isfile(revision_fn):
+            with open(revision_fn) as rf:
+                if not rf.read().strip().isdigit():
+                    # local snap, see fetch_op
+                    continue
+        channel_fn = f"{name}/channel.json"
+        if os.path.isfile(channel_fn):
+            # resolve the channel again
+            with open(channel_fn) as cf:
+                tracking = json.load(cf)
+            snaps.append(
+                snap_at_rev(
+                    name,
+                    channel=tracking["channel"],
+                    architecture=tracking["architecture"],
+                )
+            )
+            continue
+        snaps.append(snap_at_rev(name))
+    return snaps
+
+
+def prrefresh(res):
+    for change in res.get("changed", []):
+        changes = []
+        for what in ("revision", "decl-revision"):
+            rc = change.get(what)
+            if rc is None:
+                continue
+            before = rc["before"] if rc["before"] >= 0 else "-"
+            changes.append(f"{what} {before} -> {rc['after']}")
+        print(f"{change['snap']}: {', '.join(changes)}")
+    print(f"{len(res.get('unchanged', []))} unchanged")
+
+
+def prclosure(res):
+    closure = res.get("closure", [])
+    if closure:
+        print(f"closure: {' '.join(closure)}")
+    scenario = res["scenario"]
+    args = ["--arch", scenario["architecture"]]
+    for vs in scenario.get("validation-sets", []):
+        args += ["--validation-set", vs]
+    args += [scenario["target-snap"]] + (scenario["snaps"] or [])
+    print(f"scenario: {res['scenario-file']}")
+    print(f"  ifacetool auto-connections {' '.join(args)}")
 
-        if meta:
-            if snap.local_yaml is not None:
-                snap_yaml = snap.local_yaml
-                # revision was set to the local path
-            else:
-                revision, snap_yaml = f.fetch_metadata(snap)
-            with open(f"{snap.name}/snap.yaml", "w") as mf:
-                mf.write(snap_yaml)
-            with open(f"{snap.name}/revision", "w") as rf:
-                rf.write(f"{revision}\n")
 
-    if decls:
-        engine("fetch-decls", snaps=snap_names)
+def mock_store_op(directory, addr):
+    "serve snap metadata and declarations from a directory of snaps"
+    params = {"dir": directory}
+    if addr:
+        params["addr"] = addr
+    # runs until interrupted, the first line of output is the store URL
+    engine_run("mock-store", **params)
 
 
 def local_fetch(fname):
+    local_gadget_yaml = None
+    local_hooks = None
     if fname.endswith(".yaml"):
         with open(fname) as f:
             local_yaml = f.read()
     else:  # .snap
-        with tempfile.TemporaryDirectory("ifacetool-unpack") as tempdir:
-            unsquash_dir = os.path.join(tempdir, "unsquashed")
-            unsquashfs(fname, unsquash_dir, "meta/snap.yaml")
-            with open(os.path.join(unsquash_dir, "meta/snap.yaml")) as f:
-                local_yaml = f.read()
+        out = engine("read-snap", snap=fname)
+        local_yaml = out["snap-yaml"]
+        local_gadget_yaml = out.get("gadget-yaml")
+        local_hooks = out["hooks"]
     meta = yaml.safe_load(local_yaml)
     name = meta.get("name")
     if name is None:
         raise Exception(f"local {fname} has no name")
-    return snap_at_rev(name=name, local_yaml=local_yaml)
-
-
-def unsquashfs(snap_fn, unpackdir, p):
-    try:
-        subprocess.run(
-            ["unsquashfs", "-n", "-d", unpackdir, snap_fn, p],
-            check=True,
-            capture_output=True,
-        )
-    except subprocess.CalledProcessError as pe:
-        raise Exception("{}, err: {}".format(pe, pe.stderr.decode("utf8")))
+    return snap_at_rev(
+        name=name,
+        local_yaml=local_yaml,
+        local_gadget_yaml=local_gadget_yaml,
+        local_hooks=local_hooks,
+    )
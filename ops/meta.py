@@ -0,0 +1,46 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2026 Canonical Ltd.
+#
+# This program is free software; you can redistribute it and/or
+# modify it under the terms of the GNU Lesser General Public
+# License version 3 as published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+# Lesser General Public License for more details.
+#
+# You should have received a copy of the GNU Lesser General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from .simulation import simulate
+
+
+def check_meta_op(snaps, warnings, output_format):
+    "check the plugs and slots in snap.yaml with the interfaces sanitizers"
+    out = simulate("check-meta", {"snaps": list(snaps)}, output_format)
+    if out is None:
+        return
+
+    for mc in out["snaps"] or []:
+        findings = [
+            finding
+            for finding in mc["findings"] or []
+            if warnings or finding["severity"] == "error"
+        ]
+        if not findings:
+            print(f"{mc['snap']}: OK")
+            continue
+        print(f"{mc['snap']}:")
+        for finding in findings:
+            where = ""
+            if finding.get("plug"):
+                where = f"plug {finding['plug']}"
+            elif finding.get("slot"):
+                where = f"slot {finding['slot']}"
+            if finding.get("attribute"):
+                where = f"{where} [{finding['attribute']}]"
+            if where:
+                where = f" {where}:"
+            print(f"  {finding['severity']}:{where} {finding['message']}")
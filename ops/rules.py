@@ -0,0 +1,315 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2026 Canonical Ltd.
+#
+# This program is free software; you can redistribute it and/or
+# modify it under the terms of the GNU Lesser General Public
+# License version 3 as published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+# Lesser General Public License for more details.
+#
+# You should have received a copy of the GNU Lesser General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import difflib
+import json
+
+from .simulation import scenario_params, simulate
+
+
+def suggest_op(
+    plug,
+    slot,
+    context_snaps,
+    outcome,
+    show_all,
+    model,
+    store,
+    classic,
+    system,
+    architecture,
+    base_declaration,
+    validation_sets,
+    validation_mode,
+    output_format,
+    deterministic,
+    f,
+):
+    "suggest snap-declaration rules to achieve a desired connection"
+    snaps = [plug.split(":", 1)[0], slot.split(":", 1)[0]] + list(context_snaps)
+    # prepare
+    for name in set(snaps):
+        f.snap_ids(name)
+    params = scenario_params(
+        model=model,
+        store=store,
+        classic=classic,
+        system=system,
+        architecture=architecture,
+        base_declaration=base_declaration,
+        validation_sets=validation_sets,
+        validation_mode=validation_mode,
+        deterministic=deterministic,
+    )
+    params["plug"] = plug
+    params["slot"] = slot
+    params["outcome"] = outcome
+    params["snaps"] = snaps
+    out = simulate("suggest", params, output_format)
+    if out is None:
+        return
+
+    current = out["current"]
+    print(f"{slot} > {plug}: {current['verdict']}")
+    if current.get("reason"):
+        print(f"  => {current['reason']}")
+    suggs = out["suggestions"] or []
+    if not suggs:
+        return
+    n = 0
+    for sugg in suggs:
+        if not sugg["verified"] and not show_all:
+            continue
+        n += 1
+        ok = "ok" if sugg["verified"] else f"not enough: {sugg['error']}"
+        print(
+            f"{n}. {sugg['snap']}/{sugg['side']}.json"
+            f" (narrowness {sugg['narrowness']}) => {ok}"
+        )
+        rule = json.dumps({sugg["interface"]: sugg["rule"]}, indent=2, sort_keys=True)
+        for line in rule.splitlines():
+            print(f"   {line}")
+    if n == 0:
+        print("no suggestion achieves the outcome")
+
+
+verdict_rank = {"denied": 0, "manual": 1, "auto": 2}
+
+
+def over_grant_op(
+    snap,
+    proposed_plugs,
+    proposed_slots,
+    context_snaps,
+    model,
+    store,
+    classic,
+    system,
+    architecture,
+    base_declaration,
+    validation_sets,
+    validation_mode,
+    output_format,
+    deterministic,
+    f,
+):
+    "report the plug/slot pairs whose verdict changes with proposed rules"
+    snaps = [snap] + list(context_snaps)
+    # prepare
+    for name in set(snaps):
+        f.snap_ids(name)
+    params = scenario_params(
+        model=model,
+        store=store,
+        classic=classic,
+        system=system,
+        architecture=architecture,
+        base_declaration=base_declaration,
+        validation_sets=validation_sets,
+        validation_mode=validation_mode,
+        deterministic=deterministic,
+    )
+    params["snap"] = snap
+    if proposed_plugs:
+        params["proposed-plugs"] = proposed_plugs
+    if proposed_slots:
+        params["proposed-slots"] = proposed_slots
+    params["snaps"] = list(context_snaps)
+    out = simulate("over-grant", params, output_format)
+    if out is None:
+        return
+
+    changes = out["changes"] or []
+    print(f"{out['evaluated']} pairs evaluated, {len(changes)} changed")
+    for change in changes:
+        before = change["before"]
+        after = change["after"]
+        plug = f"{after['plug']['snap']}:{after['plug']['plug']}"
+        slot = f"{after['slot']['snap']}:{after['slot']['slot']}"
+        # + marks a broadened grant, - a narrowed one
+        mark = "+"
+        if verdict_rank[after["verdict"]] < verdict_rank[before["verdict"]]:
+            mark = "-"
+        what = f"{before['verdict']} -> {after['verdict']}"
+        if before["verdict"] == after["verdict"]:
+            # only a grant changed underneath the verdict, e.g. an
+            # auto-connection grant masked by ambiguity
+            key = "allow-connection"
+            if before[key] == after[key]:
+                key = "allow-auto-connection"
+            if not after[key]:
+                mark = "-"
+            what = (
+                f"{what} ({key}: {str(before[key]).lower()}"
+                f" -> {str(after[key]).lower()})"
+            )
+        print(f"{mark} {slot} > {plug}: {what}")
+        if after.get("reason"):
+            print(f"  => {after['reason']}")
+
+
+def rule_coverage_op(
+    snaps,
+    include_base,
+    dead,
+    matches,
+    model,
+    store,
+    classic,
+    system,
+    architecture,
+    base_declaration,
+    validation_sets,
+    validation_mode,
+    output_format,
+    deterministic,
+    f,
+):
+    "report which declaration rule alternatives are exercised by a set of snaps"
+    # prepare
+    for name in set(snaps):
+        f.snap_ids(name)
+    params = scenario_params(
+        model=model,
+        store=store,
+        classic=classic,
+        system=system,
+        architecture=architecture,
+        base_declaration=base_declaration,
+        validation_sets=validation_sets,
+        validation_mode=validation_mode,
+        deterministic=deterministic,
+    )
+    params["include-base"] = include_base
+    params["snaps"] = snaps
+    out = simulate("rule-coverage", params, output_format)
+    if out is None:
+        return
+
+    alts = out["alternatives"] or []
+    n_dead = 0
+    for alt in alts:
+        matched = alt["matched"] or []
+        if not matched:
+            n_dead += 1
+        elif dead:
+            continue
+        where = alt["source"]
+        if where != "base-declaration":
+            where = f"{where}/{alt['side']}.json"
+        else:
+            where = f"{where} {alt['side']}"
+        what = "never matched"
+        if matched:
+            what = f"{len(matched)} matched"
+        print(f"{where} {alt['interface']} {alt['key']}[{alt['index']}]: {what}")
+        if matches:
+            for m in matched:
+                print(f"  {m}")
+    print(f"{len(alts)} alternatives, {n_dead} never matched")
+
+
+def base_declaration_op(interfaces, base_declaration, output_format):
+    "dump or diff the base-declaration stanzas for interfaces"
+    params = {"interfaces": list(interfaces)}
+    if base_declaration:
+        params["base-declaration"] = base_declaration
+    out = simulate("base-declaration", params, output_format)
+    if out is None:
+        return
+
+    for stanza in out["stanzas"] or []:
+        if not base_declaration:
+            print(stanza["builtin-text"], end="")
+            continue
+        if not stanza["changed"]:
+            continue
+        diff = difflib.unified_diff(
+            stanza["builtin-text"].splitlines(keepends=True),
+            stanza["override-text"].splitlines(keepends=True),
+            fromfile=f"builtin {stanza['side']} {stanza['interface']}",
+            tofile=f"{base_declaration} {stanza['side']} {stanza['interface']}",
+        )
+        print("".join(diff), end="")
+
+
+def interfaces_op(interface, base_declaration, output_format):
+    "list the interfaces known to the embedded snapd and their policy"
+    params = {}
+    if interface:
+        params["interface"] = interface
+    if base_declaration:
+        params["base-declaration"] = base_declaration
+    out = simulate("interfaces", params, output_format)
+    if out is None:
+        return
+
+    for entry in out["interfaces"] or []:
+        implicit = []
+        if entry["implicit-on-core"]:
+            implicit.append("core")
+        if entry["implicit-on-classic"]:
+            implicit.append("classic")
+        flags = ""
+        if implicit:
+            flags = f" [implicit on {', '.join(implicit)}]"
+        if entry["super-privileged"]:
+            flags += " [super-privileged]"
+        print(f"{entry['name']}: {entry['summary']}{flags}")
+        for side in ("plugs", "slots"):
+            policy = entry["policy"].get(side)
+            if not policy:
+                continue
+            keys = ", ".join(f"{k}: {v}" for k, v in sorted(policy.items()))
+            print(f"  {side}: {keys}")
+        if entry.get("attributes"):
+            print(f"  attributes: {', '.join(entry['attributes'])}")
+
+
+def load_json_file(fname):
+    if fname is None:
+        return {}
+    with open(fname) as f:
+        return json.load(f)
+
+
+def eval_attrs_op(constraints, plug_attrs, slot_attrs, interface, output_format):
+    "evaluate attribute constraints against plug and slot attributes"
+    params = {
+        "constraints": load_json_file(constraints),
+        "plug-attrs": load_json_file(plug_attrs),
+        "slot-attrs": load_json_file(slot_attrs),
+    }
+    if interface:
+        params["interface"] = interface
+    out = simulate("eval-attrs", params, output_format)
+    if out is None:
+        return
+
+    print("match" if out["match"] else "no match")
+    for alt in out["alternatives"] or []:
+        print(f"alternative {alt['index']}: {'match' if alt['match'] else 'no match'}")
+        for side in ("plug", "slot"):
+            if alt.get(f"{side}-error"):
+                print(f"  {side}: {alt[side + '-error']}")
+        for trace in alt["trace"] or []:
+            constraint = json.dumps(trace["constraint"])
+            value = json.dumps(trace["value"]) if "value" in trace else "missing"
+            outcome = "ok" if trace["match"] else trace["error"]
+            print(
+                f"  {trace['side']} {trace['attribute']}: {constraint}"
+                f" vs {value} => {outcome}"
+            )
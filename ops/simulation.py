@@ -14,35 +14,145 @@
 # You should have received a copy of the GNU Lesser General Public License
 # along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
+import json
 import sys
 
-from .engine import engine
+from .engine import engine_raw
 
 
-def auto_connections_op(
-    target_snap, context_snaps, interface, candidates, model, store, classic, f
+def scenario_params(
+    model,
+    store,
+    classic,
+    system,
+    architecture,
+    base_declaration,
+    validation_sets,
+    validation_mode,
+    deterministic,
 ):
-    "simulate auto-connections"
-    to_consider = set(context_snaps) | {target_snap}
-    # prepare
-    for name in to_consider:
-        f.snap_ids(name)
     brand, model = model.split("/", 2)
     params = {
         "classic": classic,
+        "system": system,
+        "architecture": architecture,
+        "deterministic": deterministic,
         "brand": brand,
         "model": model,
-        "target-snap": target_snap,
-        "snaps": context_snaps,
     }
     if store:
         params["store"] = store
-    out = engine("auto-connections", **params)
+    if base_declaration:
+        params["base-declaration"] = base_declaration
+    if validation_sets:
+        params["validation-sets"] = list(validation_sets)
+        params["validation-mode"] = validation_mode
+    return params
+
+
+# path of the bundle to export the simulation to, see set_export
+_export_bundle = None
+
+
+def set_export(bundle):
+    "export the simulation run by the command to the bundle"
+    global _export_bundle
+    _export_bundle = bundle
+
+
+def simulate(op, params, output_format):
+    """run the simulation op, returns the result for the text report
+    otherwise outputs it directly and returns None"""
+    if output_format in ("dot", "mermaid"):
+        params["format"] = output_format
+    if _export_bundle:
+        # the output is the one recorded in the bundle
+        raw = engine_raw("export", op=op, params=params, output=_export_bundle)
+    else:
+        raw = engine_raw(op, **params)
+    if output_format in ("dot", "mermaid"):
+        sys.stdout.write(raw.decode("utf8"))
+        return None
+
+    out = json.loads(raw)
 
     if "error" in out:
         print(f'simulation: {out["error"]}', file=sys.stderr)
         sys.exit(1)
 
+    if output_format == "json":
+        print(json.dumps(out, indent=2))
+        return None
+    return out
+
+
+def prinstallation(inst):
+    inst_res = "OK"
+    if inst["error"] != "":
+        inst_res = inst["error"]
+    print(f"installing {inst['snap-name']}: {inst_res}")
+    arch_err = inst.get("architecture-error")
+    if arch_err:
+        print(f"  architecture: {arch_err}")
+    badifaces = inst.get("bad-interfaces")
+    if badifaces:
+        print(f"  bad-interfaces: {badifaces}")
+    for verr in inst.get("validation-errors", []):
+        print(f"  validation: {verr}")
+
+
+def prmissing(out):
+    for missing in out.get("missing-required", []):
+        rev = ""
+        if missing.get("revision"):
+            rev = f" at revision {missing['revision']}"
+        print(
+            f"missing {missing['snap-name']}{rev}: "
+            f"required by {missing['validation-set']}"
+        )
+
+
+def auto_connections_op(
+    target_snap,
+    context_snaps,
+    interface,
+    candidates,
+    arity,
+    model,
+    store,
+    classic,
+    system,
+    system_slots,
+    architecture,
+    base_declaration,
+    validation_sets,
+    validation_mode,
+    output_format,
+    deterministic,
+    f,
+):
+    "simulate auto-connections"
+    to_consider = set(context_snaps) | {target_snap}
+    # prepare
+    for name in to_consider:
+        f.snap_ids(name)
+    params = scenario_params(
+        model=model,
+        store=store,
+        classic=classic,
+        system=system,
+        architecture=architecture,
+        base_declaration=base_declaration,
+        validation_sets=validation_sets,
+        validation_mode=validation_mode,
+        deterministic=deterministic,
+    )
+    params["target-snap"] = target_snap
+    params["snaps"] = context_snaps
+    out = simulate("auto-connections", params, output_format)
+    if out is None:
+        return
+
     # installing issues
     installing = {}
     for inst in out["installing"]:
@@ -53,20 +163,24 @@ def auto_connections_op(
         if name in seen:
             return
         seen.add(name)
-        inst = installing[name]
-        inst_res = "OK"
-        if inst["error"] != "":
-            inst_res = inst["error"]
-        print(f"installing {name}: {inst_res}")
-        badifaces = inst.get("bad-interfaces")
-        if badifaces:
-            print(f"  bad-interfaces: {badifaces}")
+        prinstallation(installing[name])
 
     for name in context_snaps:
         if name == target_snap:
             continue
         prinst(name)
     prinst(target_snap)
+    prmissing(out)
+
+    # system
+    sys_slots = out["system-slots"]
+    if sys_slots is None:
+        sys_slots = []
+    print(f"system: {out['system-snap']} with {len(sys_slots)} implicit slots")
+    if system_slots or interface is not None:
+        for slot in sys_slots:
+            if interface is None or slot["interface"] == interface:
+                print(f"  {slot['name']}")
 
     # connections
     conns = out["connections"]
@@ -115,6 +229,7 @@ def auto_connections_op(
         prconn(conn)
 
     # dangling plugs
+    plug_arity = {pa["plug"]: pa for pa in out.get("plug-arity") or ()}
     plugs = out["plugs"]
     if plugs is None:
         plugs = []
@@ -125,9 +240,47 @@ def auto_connections_op(
         name = plug["name"]
         if name not in connected_plugs:
             print(f": {name}")
+            pa = plug_arity.get(name)
+            if pa and pa["ambiguous"]:
+                cands = ", ".join(sref(slot) for slot in pa["candidates"])
+                print(f"  => ambiguous: {cands}")
             if candidates:
                 prcandidates(out, name, other_side="slot", happy=False)
 
+    if arity:
+        prarity(out, relevant)
+
+
+def sref(slot):
+    return f"{slot['snap']}:{slot['slot']}"
+
+
+def pref(plug):
+    return f"{plug['snap']}:{plug['plug']}"
+
+
+def prarity(out, relevant):
+    print("arity:")
+    for pa in out.get("plug-arity") or ():
+        if not relevant(pa):
+            continue
+        n = len(pa["candidates"] or ())
+        spp = pa["slots-per-plug"] or "-"
+        flags = ", ambiguous" if pa["ambiguous"] else ""
+        print(f"  plug {pa['plug']}: slots-per-plug {spp}, {n} candidates{flags}")
+        for res in pa.get("resolutions") or ():
+            others = ", ".join(sref(slot) for slot in res["disallow"])
+            print(f"    to connect {sref(res['slot'])} disallow {others}")
+    for sa in out.get("slot-arity") or ():
+        if not relevant(sa):
+            continue
+        n = len(sa["candidates"] or ())
+        pps = sa["plugs-per-slot"]
+        print(f"  slot {sa['slot']}: plugs-per-slot {pps}, {n} candidates")
+        skipped = sa.get("skipped")
+        if skipped:
+            print(f"    skipped: {', '.join(pref(plug) for plug in skipped)}")
+
 
 def prcandidates(out, name, other_side, happy):
     cands = out[f"{other_side}-candidates"].get(name, ())
@@ -184,3 +337,70 @@ def ilabel(cand, side):
     if label:
         return f"{{{iface}: {label}}}"
     return ""
+
+
+def system_graph_op(
+    snaps,
+    interface,
+    model,
+    store,
+    classic,
+    system,
+    architecture,
+    base_declaration,
+    validation_sets,
+    validation_mode,
+    output_format,
+    deterministic,
+    f,
+):
+    "simulate installing a set of snaps and report all their connections"
+    # prepare
+    for name in set(snaps):
+        f.snap_ids(name)
+    params = scenario_params(
+        model=model,
+        store=store,
+        classic=classic,
+        system=system,
+        architecture=architecture,
+        base_declaration=base_declaration,
+        validation_sets=validation_sets,
+        validation_mode=validation_mode,
+        deterministic=deterministic,
+    )
+    params["snaps"] = snaps
+    out = simulate("system-graph", params, output_format)
+    if out is None:
+        return
+
+    for inst in out["installing"]:
+        prinstallation(inst)
+    prmissing(out)
+    print(f"system: {out['system-snap']}")
+
+    conns = out["connections"]
+    if conns is None:
+        conns = []
+    connected_plugs = set()
+    for conn in conns:
+        plug = f"{conn['plug']['snap']}:{conn['plug']['plug']}"
+        connected_plugs.add(plug)
+        if interface is not None and conn["interface"] != interface:
+            continue
+        slot = f"{conn['slot']['snap']}:{conn['slot']['slot']}"
+        flags = ""
+        if not conn["auto"]:
+            flags = " [manual]"
+        elif conn.get("by-gadget"):
+            flags = " [gadget]"
+        print(f"{slot} > {plug}{flags}")
+
+    # dangling plugs
+    for gsnap in out["snaps"]:
+        for plug in gsnap["plugs"] or ():
+            if interface is not None and plug["interface"] != interface:
+                continue
+            name = f"{gsnap['name']}:{plug['name']}"
+            if name not in connected_plugs:
+                print(f": {name}")